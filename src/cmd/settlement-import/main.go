@@ -0,0 +1,98 @@
+// Command settlement-import matches a vendor settlement CSV against the bridge
+// transactions of one credential, stores the report and prints it.
+//
+//	go run ./cmd/settlement-import -vendor-code MIDTR-1 -file settlement.csv [-start 2025-01-01 -end 2025-01-31]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"pg_bridge_go/config"
+	"pg_bridge_go/database"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/logger"
+	"pg_bridge_go/settlement"
+	"text/tabwriter"
+
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func main() {
+	VendorCode := flag.String("vendor-code", "", "credential code the file belongs to, e.g. MIDTR-1")
+	FilePath := flag.String("file", "", "settlement CSV exported from the vendor dashboard")
+	StartDate := flag.String("start", "", "first day of the period, YYYY-MM-DD (default: from the file)")
+	EndDate := flag.String("end", "", "last day of the period, YYYY-MM-DD (default: from the file)")
+	CreatedBy := flag.String("created-by", "cli", "name recorded on the import")
+	ShowMatched := flag.Bool("show-matched", false, "also print matched rows")
+	flag.Parse()
+
+	if *VendorCode == "" || *FilePath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	start, end, err := settlement.ParsePeriod(*StartDate, *EndDate)
+	if err != nil {
+		fail(err)
+	}
+
+	logger.Init(true)
+	defer logger.Close()
+	config.InitEnvConfig()
+
+	// Migrations are the server's job; the SQL log would end up in the report
+	db, err := database.Open(gormlogger.Warn)
+	if err != nil {
+		fail(err)
+	}
+
+	var credential db_var.PaymentGatewayCredentialT
+	if err := db.Where("code = ?", *VendorCode).First(&credential).Error; err != nil {
+		fail(fmt.Errorf("credential %s: %w", *VendorCode, err))
+	}
+
+	file, err := os.Open(*FilePath)
+	if err != nil {
+		fail(err)
+	}
+	defer file.Close()
+
+	record, err := settlement.Import(db, credential, *FilePath, file, start, end, *CreatedBy)
+	if err != nil {
+		fail(err)
+	}
+
+	fmt.Printf("Import %d for %s\n", record.ID, record.VendorCode)
+	if record.PeriodStart != nil && record.PeriodEnd != nil {
+		fmt.Printf("Period:                %s to %s\n", record.PeriodStart.Format("2006-01-02"), record.PeriodEnd.Format("2006-01-02"))
+	}
+	fmt.Printf("Rows:                  %d (%d ignored by status)\n", record.Rows, record.Ignored)
+	fmt.Printf("Matched:               %d\n", record.Matched)
+	fmt.Printf("Amount mismatched:     %d\n", record.AmountMismatched)
+	fmt.Printf("Missing in bridge:     %d\n", record.MissingInBridge)
+	fmt.Printf("Missing in settlement: %d\n", record.MissingInSettlement)
+	fmt.Printf("Gross / fee / net:     %.2f / %.2f / %.2f\n\n", record.TotalGross, record.TotalFee, record.TotalNet)
+
+	var items []settlement.Item
+	if err := json.Unmarshal(record.Items, &items); err != nil {
+		fail(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RESULT\tORDER ID\tSETTLED\tFEE\tNET\tBRIDGE\tBRIDGE STATUS")
+	for _, item := range items {
+		if item.Result == settlement.ResultMatched && !*ShowMatched {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%.2f\t%.2f\t%.2f\t%d\t%s\n",
+			item.Result, item.OrderID, item.SettlementAmount, item.Fee, item.Net, item.BridgeAmount, item.BridgeStatus)
+	}
+	w.Flush()
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "settlement-import:", err)
+	os.Exit(1)
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"pg_bridge_go/global_var"
 	"pg_bridge_go/logger"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
@@ -15,6 +17,13 @@ var (
 	MasterKey   []byte
 	CallbackUrl string
 	AppPort     string
+
+	WebhookWorkers      int
+	ExpirySweepInterval time.Duration
+
+	ReconcileInterval    time.Duration
+	ReconcileConcurrency int
+	ReconcileRate        float64
 )
 
 // InitEnvConfig loads environment variables from .env file
@@ -58,6 +67,62 @@ func LoadAppPort() {
 	AppPort = os.Getenv("APP_PORT")
 }
 
+// LoadWebhookWorkers reads how many webhook delivery workers to run, default 4
+func LoadWebhookWorkers() {
+	WebhookWorkers = 4
+	if v := os.Getenv("WEBHOOK_WORKERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			log.Panicf("WEBHOOK_WORKERS must be a positive number, got %q", v)
+		}
+		WebhookWorkers = n
+	}
+}
+
+// LoadExpirySweepInterval reads how often overdue transactions are expired, default 1m
+func LoadExpirySweepInterval() {
+	ExpirySweepInterval = time.Minute
+	if v := os.Getenv("EXPIRY_SWEEP_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Panicf("EXPIRY_SWEEP_INTERVAL must be a positive duration such as 1m, got %q", v)
+		}
+		ExpirySweepInterval = d
+	}
+}
+
+// LoadReconcileConfig reads how often open transactions are reconciled with
+// the vendors (default 15m) and the per credential limits used while doing so
+// (default 2 concurrent calls, 5 calls per second).
+func LoadReconcileConfig() {
+	ReconcileInterval = 15 * time.Minute
+	if v := os.Getenv("RECONCILE_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Panicf("RECONCILE_INTERVAL must be a positive duration such as 15m, got %q", v)
+		}
+		ReconcileInterval = d
+	}
+
+	ReconcileConcurrency = 2
+	if v := os.Getenv("RECONCILE_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			log.Panicf("RECONCILE_CONCURRENCY must be a positive number, got %q", v)
+		}
+		ReconcileConcurrency = n
+	}
+
+	ReconcileRate = 5
+	if v := os.Getenv("RECONCILE_RATE"); v != "" {
+		r, err := strconv.ParseFloat(v, 64)
+		if err != nil || r <= 0 {
+			log.Panicf("RECONCILE_RATE must be a positive number of calls per second, got %q", v)
+		}
+		ReconcileRate = r
+	}
+}
+
 func GetEncryptionKey() []byte {
 	return MasterKey
 }
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"fmt"
+	"pg_bridge_go/config"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/helper"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func CreateAPIKey(c *fiber.Ctx) error {
+	type Request struct {
+		Name      string   `json:"name"`
+		Scopes    []string `json:"scopes"`
+		ExpiresAt string   `json:"expires_at"`
+		Signing   bool     `json:"signing"`
+	}
+
+	var input Request
+	if err := c.BodyParser(&input); err != nil {
+		return helper.SendResponse(fiber.StatusBadRequest, nil, nil, c)
+	}
+
+	if len(input.Scopes) == 0 {
+		return helper.SendResponse(fiber.StatusBadRequest, "At least one scope is required", nil, c)
+	}
+	for _, scope := range input.Scopes {
+		if !slices.Contains(global_var.APIKeyScopes, scope) {
+			return helper.SendResponse(fiber.StatusBadRequest, "Invalid scope "+scope, nil, c)
+		}
+		// A key can never hold more than its issuer
+		if !slices.Contains(helper.GetScopesFiber(c), scope) {
+			return helper.SendResponse(fiber.StatusForbidden, "Cannot grant scope "+scope, nil, c)
+		}
+	}
+
+	var expiresAt *time.Time
+	if input.ExpiresAt != "" {
+		t, err := time.Parse(time.RFC3339, input.ExpiresAt)
+		if err != nil || t.Before(time.Now()) {
+			return helper.SendResponse(fiber.StatusBadRequest, "expires_at must be a future RFC3339 timestamp", nil, c)
+		}
+		expiresAt = &t
+	}
+
+	var user db_var.UserT
+	if err := global_var.DB.Where("username = ?", helper.GetUsernameFiber(c)).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return helper.SendResponse(fiber.StatusBadRequest, "User not found", nil, c)
+		}
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	key, lookup, hash, err := helper.GenerateAPIKey()
+	if err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "Failed to generate api key", nil, c)
+	}
+
+	// Signing keys also get an HMAC secret for X-PGB-Signature requests
+	var signingSecret, signingSecretEn string
+	if input.Signing {
+		signingSecret, err = helper.GenerateSigningSecret()
+		if err != nil {
+			return helper.SendResponse(fiber.StatusInternalServerError, "Failed to generate signing secret", nil, c)
+		}
+		signingSecretEn, err = helper.Encrypt(signingSecret, config.MasterKey)
+		if err != nil {
+			return helper.SendResponse(fiber.StatusInternalServerError, fmt.Sprintf("%v", err), nil, c)
+		}
+	}
+
+	apiKey := db_var.APIKeyT{
+		UserID:        user.ID,
+		UserCode:      user.Username,
+		Name:          input.Name,
+		Prefix:        lookup,
+		KeyHash:       hash,
+		SigningSecret: signingSecretEn,
+		Scopes:        strings.Join(input.Scopes, ","),
+		ExpiresAt:     expiresAt,
+		CreatedBy:     helper.GetUsernameFiber(c),
+	}
+
+	if err := global_var.DB.Create(&apiKey).Error; err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	// The plain key and signing secret are only ever returned here
+	result := fiber.Map{
+		"api_key":    key,
+		"prefix":     apiKey.Prefix,
+		"name":       apiKey.Name,
+		"scopes":     input.Scopes,
+		"expires_at": apiKey.ExpiresAt,
+	}
+	if input.Signing {
+		result["signing_secret"] = signingSecret
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "Store this key now, it will not be shown again", result, c)
+}
+
+func GetAllAPIKey(c *fiber.Ctx) error {
+	var apiKeys []db_var.APIKeyT
+	if err := global_var.DB.Where("user_code = ?", helper.GetUsernameFiber(c)).Order("created_at desc").Find(&apiKeys).Error; err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "", apiKeys, c)
+}
+
+func RevokeAPIKey(c *fiber.Ctx) error {
+	prefix := c.Params("prefix")
+
+	result := global_var.DB.Model(&db_var.APIKeyT{}).
+		Where("prefix = ? AND user_code = ? AND revoked_at IS NULL", prefix, helper.GetUsernameFiber(c)).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+	if result.RowsAffected == 0 {
+		return helper.SendResponse(fiber.StatusBadRequest, "API key not found", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "API key revoked", nil, c)
+}
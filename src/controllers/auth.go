@@ -6,6 +6,7 @@ import (
 	"pg_bridge_go/db_var"
 	"pg_bridge_go/global_var"
 	"pg_bridge_go/helper"
+	"pg_bridge_go/models"
 	"regexp"
 	"strings"
 
@@ -49,49 +50,126 @@ func validateInput(username, password string) error {
 
 func RegisterHandler(c *fiber.Ctx) error {
 	var req struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
+		Username         string `json:"username"`
+		Password         string `json:"password"`
+		Role             string `json:"role"`
+		OrganizationCode string `json:"organization_code"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
 		return helper.SendResponse(http.StatusBadRequest, "Invalid data format", nil, c)
 	}
 
+	if req.Role == "" {
+		req.Role = global_var.RoleMerchantOwner
+	}
+
+	user, status, message := createUser(req.Username, req.Password, req.Role, req.OrganizationCode, helper.GetUsernameFiber(c))
+	if user == nil {
+		return helper.SendResponse(status, message, nil, c)
+	}
+
+	organization, _ := models.GetUserOrganizationCode(user.ID, global_var.DB)
+	result := fiber.Map{
+		"id":                user.ID,
+		"username":          user.Username,
+		"role":              user.Role,
+		"organization_code": organization,
+	}
+
+	return helper.SendResponse(http.StatusOK, "Registration successful", result, c)
+}
+
+// createUser validates and stores a new user. Without an organization code the
+// user gets a personal organization named after them. On failure the user is
+// nil and the status and message describe the error.
+func createUser(username, password, role, organizationCode, createdBy string) (*db_var.UserT, int, string) {
+	if _, ok := global_var.RolePermissions[role]; !ok {
+		return nil, http.StatusBadRequest, "Invalid role"
+	}
+
 	// Input validation and sanitization
-	if err := validateInput(req.Username, req.Password); err != nil {
-		return helper.SendResponse(http.StatusBadRequest, err.Error(), nil, c)
+	if err := validateInput(username, password); err != nil {
+		return nil, http.StatusBadRequest, err.Error()
 	}
 
 	// Sanitize username
-	req.Username = strings.TrimSpace(req.Username)
+	username = strings.TrimSpace(username)
 
 	var existing db_var.UserT
-	if err := global_var.DB.Where("username = ?", req.Username).First(&existing).Error; err == nil {
-		return helper.SendResponse(http.StatusBadRequest, "Username already taken", nil, c)
+	if err := global_var.DB.Where("username = ?", username).First(&existing).Error; err == nil {
+		return nil, http.StatusBadRequest, "Username already taken"
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		return helper.SendResponse(http.StatusInternalServerError, "Database error", nil, c)
+		return nil, http.StatusInternalServerError, "Database error"
+	}
+
+	if organizationCode != "" {
+		var organization db_var.OrganizationT
+		if err := global_var.DB.Where("code = ?", organizationCode).First(&organization).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, http.StatusBadRequest, "Organization not found"
+			}
+			return nil, http.StatusInternalServerError, "Database error"
+		}
+	} else {
+		var existingOrganization db_var.OrganizationT
+		if err := global_var.DB.Where("code = ?", username).First(&existingOrganization).Error; err == nil {
+			return nil, http.StatusBadRequest, "Organization code already taken"
+		}
 	}
 
-	hashedPassword, err := helper.HashPassword(req.Password)
+	hashedPassword, err := helper.HashPassword(password)
 	if err != nil {
-		return helper.SendResponse(http.StatusInternalServerError, "Password encryption failed", nil, c)
+		return nil, http.StatusInternalServerError, "Password encryption failed"
 	}
 
 	user := db_var.UserT{
-		Username: req.Username,
+		Username: username,
 		Password: string(hashedPassword),
+		Role:     role,
 	}
 
-	if err := global_var.DB.Create(&user).Error; err != nil {
-		return helper.SendResponse(http.StatusInternalServerError, "Database error", nil, c)
+	err = global_var.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if organizationCode != "" {
+			return models.AddOrganizationMember(organizationCode, user, createdBy, tx)
+		}
+		return models.CreatePersonalOrganization(user, createdBy, tx)
+	})
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Database error"
 	}
 
-	result := fiber.Map{
-		"id":       user.ID,
-		"username": user.Username,
+	return &user, http.StatusOK, ""
+}
+
+func UpdateUserRole(c *fiber.Ctx) error {
+	var req struct {
+		Role string `json:"role"`
 	}
 
-	return helper.SendResponse(http.StatusOK, "Registration successful", result, c)
+	if err := c.BodyParser(&req); err != nil {
+		return helper.SendResponse(http.StatusBadRequest, "Invalid data format", nil, c)
+	}
+
+	if _, ok := global_var.RolePermissions[req.Role]; !ok {
+		return helper.SendResponse(http.StatusBadRequest, "Invalid role", nil, c)
+	}
+
+	result := global_var.DB.Model(&db_var.UserT{}).Where("username = ?", c.Params("username")).Update("role", req.Role)
+	if result.Error != nil {
+		return helper.SendResponse(http.StatusInternalServerError, "Database error", nil, c)
+	}
+	if result.RowsAffected == 0 {
+		return helper.SendResponse(http.StatusBadRequest, "User not found", nil, c)
+	}
+
+	return helper.SendResponse(http.StatusOK, "Role updated", fiber.Map{
+		"username": c.Params("username"),
+		"role":     req.Role,
+	}, c)
 }
 
 func Ping(ctx *fiber.Ctx) error {
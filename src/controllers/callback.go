@@ -1,12 +1,16 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"pg_bridge_go/db_var"
 	"pg_bridge_go/global_var"
 	"pg_bridge_go/helper"
 	"pg_bridge_go/logger"
 	"pg_bridge_go/models"
+	"pg_bridge_go/provider"
+	"pg_bridge_go/webhook"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
@@ -29,26 +33,48 @@ func PaymentCallback(c *fiber.Ctx) error {
 	}
 
 	var credential db_var.PaymentGatewayCredentialT
-	if err := global_var.DB.Where("code = ? AND user_code = ?", TransactionData.Vendor, TransactionData.UserCode).First(&credential).Error; err != nil {
+	if err := global_var.DB.Where("code = ? AND organization_code = ?", TransactionData.Vendor, TransactionData.OrganizationCode).First(&credential).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return helper.SendResponse(fiber.StatusBadRequest, "Credential not found", nil, c)
 		}
 		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
 	}
 
+	PG, ok := provider.ForVendorCode(TransactionData.Vendor)
+	if !ok {
+		return helper.SendResponse(fiber.StatusBadRequest, "no vendor code registered yet", nil, c)
+	}
+
 	err := global_var.DB.Transaction(func(tx *gorm.DB) error {
 		var err error
 
-		TransactionStatus, PaymentType, err := SendGetPaymentStatusToMidtrans(orderID, credential)
+		Status, err := PG.GetStatus(TransactionData, credential)
 		if err != nil {
 			return err
 		}
 
-		if TransactionStatus == "capture" || TransactionStatus == "settlement" {
-			err = models.UpdatePGTransactionStatus(orderID, TransactionStatus, PaymentType, "midtrans-callback", tx)
+		if Status.Status == global_var.TxStatusPaid {
+			previous, err := models.UpdatePGTransactionStatus(orderID, Status.Status, Status.PaymentType, PG.Name()+"-callback", models.StatusCause{
+				Source:       global_var.TxEventSourceRedirectCallback,
+				VendorStatus: Status.VendorStatus,
+				Payload:      Status.Raw,
+			}, tx)
+			if errors.Is(err, models.ErrIllegalTransition) {
+				// Paid before and refunded since, the customer did pay
+				logger.Warn("Ignoring paid status on redirect", zap.String("order_id", orderID), zap.Error(err))
+				return nil
+			}
 			if err != nil {
 				return err
 			}
+
+			if previous != Status.Status {
+				TransactionData.Status = previous
+				err = webhook.Enqueue(credential, webhook.NewStatusChangeEvent(TransactionData, Status, time.Now()), tx)
+				if err != nil {
+					return err
+				}
+			}
 		} else {
 			return fmt.Errorf("transaction not success")
 		}
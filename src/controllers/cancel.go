@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"errors"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/helper"
+	"pg_bridge_go/models"
+	"pg_bridge_go/provider"
+	"pg_bridge_go/webhook"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ClosePaymentRequestBody struct {
+	OrderID string `json:"order_id"`
+}
+
+// HandleCancelPayment cancels an unpaid payment request at the vendor so the
+// payment link can no longer be used.
+func HandleCancelPayment(c *fiber.Ctx) error {
+	return closePayment(c, global_var.TxStatusCancelled, provider.PaymentProvider.Cancel)
+}
+
+// HandleExpirePayment expires an unpaid payment request right away.
+func HandleExpirePayment(c *fiber.Ctx) error {
+	return closePayment(c, global_var.TxStatusExpired, provider.PaymentProvider.Expire)
+}
+
+// closePayment locks an open transaction, asks the vendor to close it and
+// moves it to NewStatus. The vendor status is checked first: an order that
+// was paid or closed at the vendor gets that status instead and the request
+// is rejected. An order the vendor never saw, because the customer did not
+// open the payment page, is closed on our side only.
+func closePayment(c *fiber.Ctx, NewStatus string, VendorClose func(provider.PaymentProvider, db_var.PaymentGatewayTransactionT, db_var.PaymentGatewayCredentialT) (*provider.TransactionStatus, error)) error {
+	VendorCode := c.Params("vendorcode")
+	Organization := helper.GetOrganizationFiber(c)
+	Username := helper.GetUsernameFiber(c)
+	var Req ClosePaymentRequestBody
+
+	if err := c.BodyParser(&Req); err != nil {
+		return helper.SendResponse(fiber.StatusBadRequest, nil, nil, c)
+	}
+	Req.OrderID = strings.TrimSpace(Req.OrderID)
+	if Req.OrderID == "" {
+		return helper.SendResponse(fiber.StatusBadRequest, "order_id is required", nil, c)
+	}
+
+	var credential db_var.PaymentGatewayCredentialT
+	if err := global_var.DB.Where("code = ? AND organization_code = ?", VendorCode, Organization).First(&credential).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return helper.SendResponse(fiber.StatusBadRequest, "Credential not found", nil, c)
+		}
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	PG, ok := provider.ForVendorCode(VendorCode)
+	if !ok {
+		return helper.SendResponse(fiber.StatusBadRequest, "no vendor code registered yet", nil, c)
+	}
+
+	var RejectedStatus int
+	var Rejected string
+	var VendorErr error
+	var Status *provider.TransactionStatus
+	err := global_var.DB.Transaction(func(tx *gorm.DB) error {
+		var transaction db_var.PaymentGatewayTransactionT
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id = ? AND vendor = ? AND organization_code = ?", Req.OrderID, VendorCode, Organization).
+			First(&transaction).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			RejectedStatus, Rejected = fiber.StatusBadRequest, "Transaction not found"
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if !slices.Contains(global_var.OpenTxStatuses, transaction.Status) {
+			RejectedStatus, Rejected = fiber.StatusConflict, "transaction with status "+transaction.Status+" is no longer open"
+			return nil
+		}
+
+		// The customer may have paid after our last update; a close call would
+		// then fail or, with some vendors, void a captured payment
+		Current, err := PG.GetStatus(transaction, credential)
+		if err != nil && !errors.Is(err, provider.ErrNotSupported) && !errors.Is(err, provider.ErrTransactionNotFound) {
+			VendorErr = err
+			return err
+		}
+		if err == nil && !slices.Contains(global_var.OpenTxStatuses, Current.Status) {
+			RejectedStatus, Rejected = fiber.StatusConflict, "transaction has been "+Current.Status+" at the vendor"
+			return applyClosedVendorStatus(transaction, credential, Current, Username, tx)
+		}
+
+		Status, err = VendorClose(PG, transaction, credential)
+		if errors.Is(err, provider.ErrNotSupported) {
+			RejectedStatus, Rejected = fiber.StatusBadRequest, "this vendor does not support closing payments"
+			return nil
+		}
+		if errors.Is(err, provider.ErrTransactionNotFound) {
+			Status = &provider.TransactionStatus{OrderID: transaction.OrderID}
+		} else if err != nil {
+			VendorErr = err
+			return err
+		}
+
+		if Status.Status == global_var.TxStatusPaid {
+			RejectedStatus, Rejected = fiber.StatusConflict, "transaction has been paid at the vendor"
+			return applyClosedVendorStatus(transaction, credential, Status, Username, tx)
+		}
+		Status.Status = NewStatus
+
+		if _, err := models.UpdatePGTransactionStatus(transaction.OrderID, NewStatus, "", Username, models.StatusCause{
+			Source:       global_var.TxEventSourceManual,
+			VendorStatus: Status.VendorStatus,
+			Payload:      Status.Raw,
+		}, tx); err != nil {
+			return err
+		}
+
+		return webhook.Enqueue(credential, webhook.NewStatusChangeEvent(transaction, Status, time.Now()), tx)
+	})
+	if VendorErr != nil {
+		return helper.SendResponse(fiber.StatusBadGateway, VendorErr.Error(), nil, c)
+	}
+	if err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+	if Rejected != "" {
+		return helper.SendResponse(RejectedStatus, Rejected, nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "", fiber.Map{
+		"order_id":      Req.OrderID,
+		"status":        Status.Status,
+		"vendor_status": Status.VendorStatus,
+	}, c)
+}
+
+// applyClosedVendorStatus records a status the vendor reported while closing
+// a payment and queues the merchant webhook for it.
+func applyClosedVendorStatus(transaction db_var.PaymentGatewayTransactionT, credential db_var.PaymentGatewayCredentialT, Status *provider.TransactionStatus, Username string, tx *gorm.DB) error {
+	if _, err := models.UpdatePGTransactionStatus(transaction.OrderID, Status.Status, Status.PaymentType, Username, models.StatusCause{
+		Source:       global_var.TxEventSourceManual,
+		VendorStatus: Status.VendorStatus,
+		Payload:      Status.Raw,
+	}, tx); err != nil {
+		return err
+	}
+
+	return webhook.Enqueue(credential, webhook.NewStatusChangeEvent(transaction, Status, time.Now()), tx)
+}
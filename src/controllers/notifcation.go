@@ -1,32 +1,237 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"pg_bridge_go/db_var"
 	"pg_bridge_go/global_var"
 	"pg_bridge_go/helper"
+	"pg_bridge_go/logger"
 	"pg_bridge_go/models"
-	"strings"
+	"pg_bridge_go/provider"
+	"pg_bridge_go/webhook"
+	"slices"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
+// notificationResult is the outcome of processing one stored notification.
+type notificationResult struct {
+	HTTPStatus     int
+	Message        interface{}
+	SecurityEvent  string
+	SecurityFields []zap.Field
+}
+
+// HandlePostNotificationFromPG stores the notification in the inbox before
+// anything else, so it can be inspected and replayed whatever happens next.
+// Headers carrying vendor secrets are stored redacted.
 func HandlePostNotificationFromPG(c *fiber.Ctx) error {
-	VendorCode := c.Params("vendorcode")
-	VendorPrefix := strings.SplitN(VendorCode, "-", 2)[0]
+	ReceivedHeaders := helper.GetRequestHeaders(c)
+	Headers, err := json.Marshal(provider.RedactNotificationHeaders(ReceivedHeaders))
+	if err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	inbox := db_var.NotificationInboxT{
+		VendorCode:   c.Params("vendorcode"),
+		Path:         c.Path(),
+		Headers:      datatypes.JSON(Headers),
+		Body:         string(c.Body()),
+		SourceIP:     c.IP(),
+		Verification: global_var.NotificationVerificationPending,
+		Outcome:      global_var.NotificationOutcomeReceived,
+	}
+	if err := models.InsertNotificationInbox(&inbox, global_var.DB); err != nil {
+		// Without a stored copy the vendor has to send it again
+		logger.Error("Failed to store notification", zap.String("vendor_code", inbox.VendorCode), zap.Error(err))
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	Result := processNotification(&inbox, ReceivedHeaders, false)
+	if Result.SecurityEvent != "" {
+		logSecurityEvent(Result.SecurityEvent, c, Result.SecurityFields...)
+	}
+	if err := models.UpdateNotificationInboxResult(inbox, false, global_var.DB); err != nil {
+		logger.Error("Failed to record notification outcome", zap.Uint64("inbox_id", inbox.ID), zap.Error(err))
+	}
+
+	return helper.SendResponse(Result.HTTPStatus, Result.Message, nil, c)
+}
+
+// processNotification verifies and applies a stored notification and fills in
+// its verification and outcome. It is used for live notifications, checked
+// with the headers as received, and replays, which rely on the signature check
+// made at receipt because the stored secret headers are redacted.
+func processNotification(inbox *db_var.NotificationInboxT, Headers http.Header, replay bool) notificationResult {
+	VendorCode := inbox.VendorCode
+	result := func(Outcome string, HTTPStatus int, Message interface{}, Detail string) notificationResult {
+		inbox.Outcome = Outcome
+		inbox.OutcomeDetail = Detail
+		inbox.ResponseCode = HTTPStatus
+		return notificationResult{HTTPStatus: HTTPStatus, Message: Message}
+	}
+	inbox.Verification = global_var.NotificationVerificationFailed
+
+	PG, ok := provider.ForVendorCode(VendorCode)
+	if !ok {
+		return result(global_var.NotificationOutcomeRejected, fiber.StatusBadRequest, "no vendor code registered yet", "no vendor code registered yet")
+	}
+
+	var credential db_var.PaymentGatewayCredentialT
+	if err := global_var.DB.Where("code = ?", VendorCode).First(&credential).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return result(global_var.NotificationOutcomeRejected, fiber.StatusBadRequest, "Credential not found", "credential not found")
+		}
+		return result(global_var.NotificationOutcomeError, fiber.StatusInternalServerError, "", err.Error())
+	}
+	inbox.OrganizationCode = credential.OrganizationCode
 
-	switch VendorPrefix {
-	case global_var.PGVendor.Midtrans:
-		var QueryParam MidtransNotificationStruct
-		if err := c.BodyParser(&QueryParam); err != nil {
-			return helper.SendResponse(fiber.StatusBadRequest, fiber.Map{"error": err.Error() + " Error BindingJSON"}, nil, c)
+	if replay {
+		if err := json.Unmarshal(inbox.Headers, &Headers); err != nil {
+			return result(global_var.NotificationOutcomeError, fiber.StatusInternalServerError, "", "stored headers: "+err.Error())
 		}
+	}
+
+	Notification, err := PG.ParseNotification(provider.NotificationRequest{
+		Path:     inbox.Path,
+		Headers:  Headers,
+		Body:     []byte(inbox.Body),
+		Verified: replay && inbox.SignatureVerified,
+	}, credential)
+	if errors.Is(err, provider.ErrInvalidSignature) {
+		inbox.Verification = global_var.NotificationVerificationInvalid
+		Result := result(global_var.NotificationOutcomeRejected, fiber.StatusUnauthorized, "Invalid signature", err.Error())
+		Result.SecurityEvent = "notification_signature_mismatch"
+		Result.SecurityFields = []zap.Field{zap.String("vendor_code", VendorCode)}
+		return Result
+	}
+	if err != nil {
+		return result(global_var.NotificationOutcomeRejected, fiber.StatusBadRequest, fiber.Map{"error": err.Error() + " Error BindingJSON"}, err.Error())
+	}
+	inbox.Verification = global_var.NotificationVerificationVerified
+	if !replay {
+		inbox.SignatureVerified = true
+	}
+	inbox.OrderID = Notification.OrderID
 
-		if QueryParam.TransactionStatus == "settlement" || QueryParam.TransactionStatus == "capture" {
-			err := models.UpdatePGTransactionStatus(QueryParam.OrderID, QueryParam.TransactionStatus, QueryParam.PaymentType, "midtrans-callback", global_var.DB)
-			if err != nil {
-				return helper.SendResponse(fiber.StatusInternalServerError, fiber.Map{"error": "Failed to update status: " + err.Error()}, nil, c)
-			}
+	TransactionData, err := verifyNotificationTransaction(VendorCode, Notification)
+	if err != nil {
+		Result := result(global_var.NotificationOutcomeRejected, fiber.StatusBadRequest, err.Error(), err.Error())
+		Result.SecurityEvent = "notification_transaction_mismatch"
+		Result.SecurityFields = []zap.Field{
+			zap.String("vendor_code", VendorCode),
+			zap.String("order_id", Notification.OrderID),
+			zap.String("gross_amount", Notification.GrossAmount),
+			zap.Error(err),
 		}
+		return Result
 	}
 
-	return helper.SendResponse(fiber.StatusOK, fiber.Map{"message": "Notification handled"}, nil, c)
+	// Vendors resend notifications; a resend is acknowledged but not applied
+	// again. Replays skip the check, re-running is their whole point.
+	TransactionID := Notification.TransactionID
+	if TransactionID == "" {
+		TransactionID = Notification.OrderID
+	}
+
+	Outcome, Detail := global_var.NotificationOutcomeApplied, ""
+	err = global_var.DB.Transaction(func(tx *gorm.DB) error {
+		first, err := models.MarkNotificationProcessed(&db_var.ProcessedNotificationT{
+			VendorCode:    VendorCode,
+			TransactionID: TransactionID,
+			Status:        Notification.Status,
+			VendorStatus:  Notification.VendorStatus,
+			OrderID:       Notification.OrderID,
+			InboxID:       inbox.ID,
+		}, tx)
+		if err != nil {
+			return err
+		}
+		if !first && !replay {
+			Outcome, Detail = global_var.NotificationOutcomeDuplicate, "already processed "+Notification.Status+" ("+Notification.VendorStatus+") for vendor transaction "+TransactionID
+			return models.IncrementDuplicateNotifications(VendorCode, tx)
+		}
+
+		// Open statuses carry no news; everything else goes through the state
+		// machine, which refuses e.g. a late pending after paid
+		if slices.Contains(global_var.OpenTxStatuses, Notification.Status) {
+			Outcome, Detail = global_var.NotificationOutcomeIgnored, "vendor status "+Notification.VendorStatus+" leaves the transaction open"
+			return nil
+		}
+
+		previous, err := models.UpdatePGTransactionStatus(Notification.OrderID, Notification.Status, Notification.PaymentType, PG.Name()+"-callback", models.StatusCause{
+			Source:       global_var.TxEventSourceNotification,
+			VendorStatus: Notification.VendorStatus,
+			PayloadRef:   fmt.Sprintf("notification_inbox:%d", inbox.ID),
+			Payload:      Notification.Raw,
+		}, tx)
+		if errors.Is(err, models.ErrIllegalTransition) {
+			logger.Warn("Ignoring notification status",
+				zap.String("vendor_code", VendorCode),
+				zap.String("vendor_status", Notification.VendorStatus),
+				zap.Error(err),
+			)
+			Outcome, Detail = global_var.NotificationOutcomeIgnored, err.Error()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if previous == Notification.Status {
+			Outcome, Detail = global_var.NotificationOutcomeIgnored, "transaction already "+previous
+			return nil
+		}
+		Detail = previous + " -> " + Notification.Status
+		TransactionData.Status = previous
+		return webhook.Enqueue(credential, webhook.NewStatusChangeEvent(*TransactionData, Notification, time.Now()), tx)
+	})
+	if err != nil {
+		return result(global_var.NotificationOutcomeError, fiber.StatusInternalServerError, fiber.Map{"error": "Failed to update status: " + err.Error()}, err.Error())
+	}
+
+	return result(Outcome, fiber.StatusOK, fiber.Map{"message": "Notification handled"}, Detail)
+}
+
+// verifyNotificationTransaction makes sure the notified order belongs to the
+// vendor code in the URL and that the notified amount is the stored amount.
+func verifyNotificationTransaction(VendorCode string, Notification *provider.TransactionStatus) (*db_var.PaymentGatewayTransactionT, error) {
+	var TransactionData db_var.PaymentGatewayTransactionT
+	if err := global_var.DB.Where("order_id = ? AND vendor = ?", Notification.OrderID, VendorCode).First(&TransactionData).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.New("transaction not found for this vendor code")
+		}
+		return nil, err
+	}
+
+	if Notification.GrossAmount != "" {
+		GrossAmount, err := strconv.ParseFloat(Notification.GrossAmount, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gross amount %q", Notification.GrossAmount)
+		}
+		if math.Abs(GrossAmount-float64(TransactionData.Amount)) > 0.001 {
+			return nil, errors.New("gross amount does not match transaction amount")
+		}
+	}
+
+	return &TransactionData, nil
+}
+
+// logSecurityEvent records a rejected inbound request for later investigation
+func logSecurityEvent(event string, c *fiber.Ctx, fields ...zap.Field) {
+	fields = append([]zap.Field{
+		zap.String("security_event", event),
+		zap.String("ip", c.IP()),
+		zap.String("path", c.Path()),
+	}, fields...)
+	logger.Error("Security event", fields...)
 }
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"errors"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/helper"
+	"pg_bridge_go/logger"
+	"pg_bridge_go/models"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// maxNotificationReplay caps how many notifications one bulk replay re-runs.
+const maxNotificationReplay = 100
+
+type ReplayNotificationRequest struct {
+	VendorCode string `json:"vendor_code"`
+	OrderID    string `json:"order_id"`
+	// Outcome limits the replay to notifications that ended this way, e.g. error.
+	Outcome string `json:"outcome"`
+}
+
+type NotificationReplayResult struct {
+	ID            uint64 `json:"id"`
+	Verification  string `json:"verification"`
+	Outcome       string `json:"outcome"`
+	OutcomeDetail string `json:"outcome_detail"`
+	ResponseCode  int    `json:"response_code"`
+}
+
+func GetAllNotificationInbox(c *fiber.Ctx) error {
+	Limit, err := strconv.Atoi(c.Query("limit", "50"))
+	if err != nil || Limit < 1 || Limit > 200 {
+		return helper.SendResponse(fiber.StatusBadRequest, "limit must be between 1 and 200", nil, c)
+	}
+
+	db := global_var.DB.Model(&db_var.NotificationInboxT{}).Omit("headers", "body")
+	if VendorCode := c.Query("vendor_code"); VendorCode != "" {
+		db = db.Where("vendor_code = ?", VendorCode)
+	}
+	if OrderID := c.Query("order_id"); OrderID != "" {
+		db = db.Where("order_id = ?", OrderID)
+	}
+	if Outcome := c.Query("outcome"); Outcome != "" {
+		db = db.Where("outcome = ?", Outcome)
+	}
+	if Verification := c.Query("verification"); Verification != "" {
+		db = db.Where("verification = ?", Verification)
+	}
+
+	var notifications []db_var.NotificationInboxT
+	if err := db.Order("id desc").Limit(Limit).Find(&notifications).Error; err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "", notifications, c)
+}
+
+func GetNotificationInbox(c *fiber.Ctx) error {
+	inbox, status, message := findNotificationInbox(c)
+	if inbox == nil {
+		return helper.SendResponse(status, message, nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "", inbox, c)
+}
+
+// ReplayNotificationInbox processes a stored notification again. The signature
+// is only checked again when it did not check out on arrival.
+func ReplayNotificationInbox(c *fiber.Ctx) error {
+	inbox, status, message := findNotificationInbox(c)
+	if inbox == nil {
+		return helper.SendResponse(status, message, nil, c)
+	}
+
+	Result, err := replayNotification(inbox)
+	if err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "Notification replayed", Result, c)
+}
+
+// ReplayNotifications re-runs the stored notifications of a vendor code or an
+// order, oldest first.
+func ReplayNotifications(c *fiber.Ctx) error {
+	var Req ReplayNotificationRequest
+	if err := c.BodyParser(&Req); err != nil {
+		return helper.SendResponse(fiber.StatusBadRequest, nil, nil, c)
+	}
+	Req.VendorCode = strings.TrimSpace(Req.VendorCode)
+	Req.OrderID = strings.TrimSpace(Req.OrderID)
+	if Req.VendorCode == "" && Req.OrderID == "" {
+		return helper.SendResponse(fiber.StatusBadRequest, "vendor_code or order_id is required", nil, c)
+	}
+
+	db := global_var.DB.Model(&db_var.NotificationInboxT{})
+	if Req.VendorCode != "" {
+		db = db.Where("vendor_code = ?", Req.VendorCode)
+	}
+	if Req.OrderID != "" {
+		db = db.Where("order_id = ?", Req.OrderID)
+	}
+	if Req.Outcome != "" {
+		db = db.Where("outcome = ?", Req.Outcome)
+	}
+
+	var notifications []db_var.NotificationInboxT
+	if err := db.Order("id").Limit(maxNotificationReplay).Find(&notifications).Error; err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	Results := []NotificationReplayResult{}
+	for i := range notifications {
+		Result, err := replayNotification(&notifications[i])
+		if err != nil {
+			return helper.SendResponse(fiber.StatusInternalServerError, "", Results, c)
+		}
+		Results = append(Results, Result)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, strconv.Itoa(len(Results))+" notifications replayed", Results, c)
+}
+
+func replayNotification(inbox *db_var.NotificationInboxT) (NotificationReplayResult, error) {
+	processNotification(inbox, nil, true)
+	if err := models.UpdateNotificationInboxResult(*inbox, true, global_var.DB); err != nil {
+		logger.Error("Failed to record notification replay", zap.Uint64("inbox_id", inbox.ID), zap.Error(err))
+		return NotificationReplayResult{}, err
+	}
+
+	return NotificationReplayResult{
+		ID:            inbox.ID,
+		Verification:  inbox.Verification,
+		Outcome:       inbox.Outcome,
+		OutcomeDetail: inbox.OutcomeDetail,
+		ResponseCode:  inbox.ResponseCode,
+	}, nil
+}
+
+func findNotificationInbox(c *fiber.Ctx) (*db_var.NotificationInboxT, int, string) {
+	ID, err := c.ParamsInt("id")
+	if err != nil {
+		return nil, fiber.StatusBadRequest, "Invalid notification id"
+	}
+
+	var inbox db_var.NotificationInboxT
+	if err := global_var.DB.Where("id = ?", ID).First(&inbox).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.StatusBadRequest, "Notification not found"
+		}
+		return nil, fiber.StatusInternalServerError, ""
+	}
+	return &inbox, 0, ""
+}
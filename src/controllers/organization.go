@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"errors"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/helper"
+	"pg_bridge_go/models"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func CreateOrganization(c *fiber.Ctx) error {
+	type Request struct {
+		Code string `json:"code"`
+		Name string `json:"name"`
+	}
+
+	var input Request
+	if err := c.BodyParser(&input); err != nil {
+		return helper.SendResponse(fiber.StatusBadRequest, nil, nil, c)
+	}
+
+	input.Code = strings.TrimSpace(input.Code)
+	if len(input.Code) < 3 || len(input.Code) > 50 || !regexp.MustCompile(`^[a-zA-Z0-9_-]+$`).MatchString(input.Code) {
+		return helper.SendResponse(fiber.StatusBadRequest, "code must be 3 to 50 letters, numbers, underscores or dashes", nil, c)
+	}
+
+	var existing db_var.OrganizationT
+	if err := global_var.DB.Where("code = ?", input.Code).First(&existing).Error; err == nil {
+		return helper.SendResponse(fiber.StatusBadRequest, "Organization code already taken", nil, c)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	organization := db_var.OrganizationT{
+		Code:      input.Code,
+		Name:      input.Name,
+		CreatedBy: helper.GetUsernameFiber(c),
+	}
+
+	if err := global_var.DB.Create(&organization).Error; err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "", organization, c)
+}
+
+// MoveOrganizationMember moves an existing user into an organization (admin only)
+func MoveOrganizationMember(c *fiber.Ctx) error {
+	type Request struct {
+		Username string `json:"username"`
+	}
+
+	var input Request
+	if err := c.BodyParser(&input); err != nil {
+		return helper.SendResponse(fiber.StatusBadRequest, nil, nil, c)
+	}
+
+	var organization db_var.OrganizationT
+	if err := global_var.DB.Where("code = ?", c.Params("code")).First(&organization).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return helper.SendResponse(fiber.StatusBadRequest, "Organization not found", nil, c)
+		}
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	var user db_var.UserT
+	if err := global_var.DB.Where("username = ?", input.Username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return helper.SendResponse(fiber.StatusBadRequest, "User not found", nil, c)
+		}
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	err := global_var.DB.Transaction(func(tx *gorm.DB) error {
+		return models.AddOrganizationMember(organization.Code, user, helper.GetUsernameFiber(c), tx)
+	})
+	if err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "Member added", fiber.Map{
+		"organization_code": organization.Code,
+		"username":          user.Username,
+	}, c)
+}
+
+func GetOrganization(c *fiber.Ctx) error {
+	var organization db_var.OrganizationT
+	if err := global_var.DB.Where("code = ?", helper.GetOrganizationFiber(c)).First(&organization).Error; err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	type MemberStruct struct {
+		Username string `json:"username"`
+		Role     string `json:"role"`
+	}
+
+	var members []MemberStruct
+	err := global_var.DB.Model(&db_var.OrganizationMemberT{}).
+		Select("m.username, u.role").
+		Table(db_var.TableName.OrgMembers+" m").
+		Joins(`JOIN "`+db_var.TableName.User+`" u ON u.id = m.user_id`).
+		Where("m.organization_code = ?", organization.Code).
+		Order("m.username").
+		Scan(&members).Error
+	if err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "", fiber.Map{
+		"code":    organization.Code,
+		"name":    organization.Name,
+		"members": members,
+	}, c)
+}
+
+// CreateOrganizationMember creates a new user inside the caller's organization
+func CreateOrganizationMember(c *fiber.Ctx) error {
+	type Request struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Role     string `json:"role"`
+	}
+
+	var input Request
+	if err := c.BodyParser(&input); err != nil {
+		return helper.SendResponse(fiber.StatusBadRequest, nil, nil, c)
+	}
+
+	// Merchants can only hand out merchant roles
+	merchantRoles := []string{global_var.RoleMerchantOwner, global_var.RoleMerchantDeveloper, global_var.RoleFinance}
+	if !slices.Contains(merchantRoles, input.Role) {
+		return helper.SendResponse(fiber.StatusBadRequest, "Invalid role", nil, c)
+	}
+
+	user, status, message := createUser(input.Username, input.Password, input.Role, helper.GetOrganizationFiber(c), helper.GetUsernameFiber(c))
+	if user == nil {
+		return helper.SendResponse(status, message, nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "Member created", fiber.Map{
+		"id":                user.ID,
+		"username":          user.Username,
+		"role":              user.Role,
+		"organization_code": helper.GetOrganizationFiber(c),
+	}, c)
+}
+
+// RemoveOrganizationMember removes a user from the caller's organization. The
+// user can no longer authenticate until an admin assigns a new organization.
+func RemoveOrganizationMember(c *fiber.Ctx) error {
+	username := c.Params("username")
+	if username == helper.GetUsernameFiber(c) {
+		return helper.SendResponse(fiber.StatusBadRequest, "You cannot remove yourself", nil, c)
+	}
+
+	result := global_var.DB.
+		Where("username = ? AND organization_code = ?", username, helper.GetOrganizationFiber(c)).
+		Delete(&db_var.OrganizationMemberT{})
+	if result.Error != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+	if result.RowsAffected == 0 {
+		return helper.SendResponse(fiber.StatusBadRequest, "Member not found", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "Member removed", nil, c)
+}
@@ -1,237 +1,139 @@
 package controllers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"pg_bridge_go/config"
 	"pg_bridge_go/db_var"
 	"pg_bridge_go/global_var"
 	"pg_bridge_go/helper"
 	"pg_bridge_go/models"
+	"pg_bridge_go/provider"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
-type PaymentRequest struct {
-	OrderID         string            `json:"order_id"`
-	Amount          int               `json:"amount"`
-	Items           *[]PaymentItem    `json:"items,omitempty"`
-	Customer        *CustomerInfo     `json:"customer,omitempty"`
-	EnabledPayments *[]string         `json:"enabled_payments,omitempty"`
-	Callbacks       *CallbackURLs     `json:"callbacks,omitempty"`
-	Expiry          *PaymentExpiry    `json:"expiry,omitempty"`
-	CustomFields    map[string]string `json:"custom_fields,omitempty"`
-	Metadata        map[string]string `json:"metadata,omitempty"`
-}
-
-type PaymentItem struct {
-	ID       string `json:"id"`
-	Name     string `json:"name"`
-	Price    int    `json:"price"`
-	Quantity int    `json:"quantity"`
-	Brand    string `json:"brand,omitempty"`
-	Category string `json:"category,omitempty"`
-}
-
-type CustomerInfo struct {
-	FirstName string   `json:"first_name"`
-	LastName  string   `json:"last_name"`
-	Email     string   `json:"email"`
-	Phone     string   `json:"phone"`
-	Billing   *Address `json:"billing,omitempty"`
-	Shipping  *Address `json:"shipping,omitempty"`
-}
-
-type Address struct {
-	FirstName   string `json:"first_name"`
-	LastName    string `json:"last_name"`
-	Email       string `json:"email"`
-	Phone       string `json:"phone"`
-	AddressLine string `json:"address"`
-	City        string `json:"city"`
-	PostalCode  string `json:"postal_code"`
-	CountryCode string `json:"country_code"`
-}
-
-type CallbackURLs struct {
-	Finish string `json:"finish"`
-}
-
-type PaymentExpiry struct {
-	StartTime string `json:"start_time"`
-	Unit      string `json:"unit"` // e.g. "minutes"
-	Duration  int    `json:"duration"`
-}
-
 func HandleCreatePayment(c *fiber.Ctx) error {
 	VendorCode := c.Params("vendorcode")
-	var Req PaymentRequest
-	var RedirectUrl string
+	var Req provider.PaymentRequest
+	var Result *provider.PaymentResult
 
 	if err := c.BodyParser(&Req); err != nil {
 		return helper.SendResponse(fiber.StatusBadRequest, nil, nil, c)
 	}
 
-	OrderID := ""
-	if Req.OrderID == "" {
-		OrderID = helper.GenerateOrderID(VendorCode)
-	} else {
-		OrderID = Req.OrderID
-	}
-
 	var credential db_var.PaymentGatewayCredentialT
-	if err := global_var.DB.Where("code = ? AND user_code = ?", VendorCode, helper.GetUsernameFiber(c)).First(&credential).Error; err != nil {
+	if err := global_var.DB.Where("code = ? AND organization_code = ?", VendorCode, helper.GetOrganizationFiber(c)).First(&credential).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return helper.SendResponse(fiber.StatusBadRequest, "Credential not found", nil, c)
 		}
 		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
 	}
 
-	VendorPrefix := strings.SplitN(VendorCode, "-", 2)[0]
+	PG, ok := provider.ForVendorCode(VendorCode)
+	if !ok {
+		return helper.SendResponse(fiber.StatusBadRequest, "no vendor code registered yet", nil, c)
+	}
 
-	switch VendorPrefix {
-	case global_var.PGVendor.Midtrans:
-		RequestBody := MidtransTransactionRequest{
-			TransactionDetails: MidtransTransactionDetails{
-				OrderID:     OrderID,
-				GrossAmount: Req.Amount,
-			},
-			CreditCard: MidtransCreditCard{Secure: true},
+	// A retry of a failed request keeps the order id generated the first
+	// time, so a vendor that already created the payment sees the same order
+	if Req.OrderID == "" {
+		Req.OrderID = helper.GetIdempotentOrderIDFiber(c)
+	}
+	if Req.OrderID == "" {
+		var err error
+		Req.OrderID, err = helper.GenerateOrderID(credential.OrderIDTemplate, credential.OrderIDPrefix, VendorCode, time.Now(), func() (int64, error) {
+			return models.NextOrderIDSequence(global_var.DB)
+		})
+		if err != nil {
+			return helper.SendResponse(fiber.StatusInternalServerError, "Failed to generate order id: "+err.Error(), nil, c)
 		}
-
-		// Only add ItemDetails if exists
-		if Req.Items != nil && len(*Req.Items) > 0 {
-			var itemDetails []MidtransItemDetail
-			for i, v := range *Req.Items {
-				item := MidtransItemDetail{
-					ID:       fmt.Sprintf("%d", i+1),
-					Price:    v.Price,
-					Quantity: v.Quantity,
-					Name:     v.Name,
-				}
-				if v.Brand != "" {
-					item.Brand = &v.Brand
-				}
-				if v.Category != "" {
-					item.Category = &v.Category
-				}
-				itemDetails = append(itemDetails, item)
+		if KeyID := helper.GetIdempotencyKeyIDFiber(c); KeyID != 0 {
+			if err := models.BindIdempotencyOrderID(KeyID, Req.OrderID, global_var.DB); err != nil {
+				return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
 			}
-			RequestBody.ItemDetails = &itemDetails
 		}
+	}
+	if err := PG.OrderIDRules().Check(Req.OrderID); err != nil {
+		return helper.SendResponse(fiber.StatusBadRequest, err.Error(), nil, c)
+	}
 
-		// Only add CustomerDetails if exists and valid
-		if Req.Customer != nil && Req.Customer.Email != "" {
-			customer := MidtransCustomerDetails{
-				FirstName: Req.Customer.FirstName,
-				LastName:  Req.Customer.LastName,
-				Email:     Req.Customer.Email,
-				Phone:     Req.Customer.Phone,
-			}
-			if Req.Customer.Billing.Email != "" {
-				customer.BillingAddress = MidtransAddress{
-					FirstName:   Req.Customer.Billing.FirstName,
-					LastName:    Req.Customer.Billing.LastName,
-					Email:       Req.Customer.Billing.Email,
-					Phone:       Req.Customer.Billing.Phone,
-					Address:     Req.Customer.Billing.AddressLine,
-					City:        Req.Customer.Billing.City,
-					PostalCode:  Req.Customer.Billing.PostalCode,
-					CountryCode: Req.Customer.Billing.CountryCode,
-				}
-				customer.ShippingAddress = customer.BillingAddress
-			}
-			RequestBody.CustomerDetails = &customer
+	err := global_var.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+
+		insert := db_var.PaymentGatewayTransactionT{
+			OrderID:          Req.OrderID,
+			UserCode:         helper.GetUsernameFiber(c),
+			OrganizationCode: helper.GetOrganizationFiber(c),
+			Amount:           Req.Amount,
+			Vendor:           VendorCode,
+			Status:           global_var.TxStatusPending,
+			CreatedAt:        time.Now(),
+			CreatedBy:        helper.GetUsernameFiber(c),
 		}
 
-		// Only add Expiry if duration >= 20
-		if Req.Expiry != nil && Req.Expiry.Unit != "" {
-			RequestBody.Expiry = &MidtransExpiry{
-				Unit:     Req.Expiry.Unit,
-				Duration: Req.Expiry.Duration,
+		// Vendors start the expiry clock when the payment is created
+		if Req.Expiry != nil {
+			if ExpiresAt, ok := Req.Expiry.Deadline(insert.CreatedAt); ok {
+				insert.ExpiryStart = &insert.CreatedAt
+				insert.ExpiryUnit = Req.Expiry.Unit
+				insert.ExpiryDuration = Req.Expiry.Duration
+				insert.ExpiresAt = &ExpiresAt
 			}
 		}
 
-		// Add Callbacks
-		var cbUrl string
-		if credential.CallbackRedirect == 1 && Req.Callbacks != nil && Req.Callbacks.Finish != "" {
-			cbUrl = Req.Callbacks.Finish
-		} else {
-			cbUrl = config.CallbackUrl + "/callback/" + VendorCode + "/payment"
+		if Req.Customer != nil {
+			insert.CustomerName = Req.Customer.FirstName
+			insert.CustomerEmail = Req.Customer.Email
+			insert.CustomerPhone = Req.Customer.Phone
 		}
-		RequestBody.Callbacks = &MidtransCallbacks{Finish: &cbUrl}
-
-		// Add enabled payments
-		if Req.EnabledPayments != nil {
-			RequestBody.EnabledPayments = Req.EnabledPayments
-		} else {
-			// fallback to default list
-			RequestBody.EnabledPayments = &[]string{"credit_card", "mandiri_clickpay", "cimb_clicks", "bca_klikbca", "bca_klikpay", "bri_epay", "echannel", "mandiri_ecash", "permata_va", "bca_va", "bni_va", "other_va", "gopay", "indomaret", "alfamart", "danamon_online", "akulaku"}
-		}
-
-		err := global_var.DB.Transaction(func(tx *gorm.DB) error {
-			var err error
-
-			insert := db_var.PaymentGatewayTransactionT{
-				OrderID:   OrderID,
-				UserCode:  helper.GetUsernameFiber(c),
-				Amount:    Req.Amount,
-				Vendor:    VendorCode,
-				Status:    "pending",
-				CreatedAt: time.Now(),
-				CreatedBy: helper.GetUsernameFiber(c),
-			}
-
-			if Req.Customer != nil {
-				insert.CustomerName = Req.Customer.FirstName
-				insert.CustomerEmail = Req.Customer.Email
-				insert.CustomerPhone = Req.Customer.Phone
-			}
-
-			err = models.InsertPGTransaction(&insert, tx)
-			if err != nil {
-				return err
-			}
 
-			RedirectUrl, err = SendRequestPaymentToMidtrans(RequestBody, credential)
-			if err != nil {
-				return err
-			}
-
-			RedirectURLByte, err := json.Marshal([]string{RedirectUrl})
-			if err != nil {
-				return err
-			}
-			insert.CallbacksJSON = datatypes.JSON(RedirectURLByte)
-
-			err = models.UpdatePGTransactionStatus(insert.OrderID, "pending", "", helper.GetUsernameFiber(c), tx)
-			if err != nil {
-				return err
-			}
-
-			return nil
-		})
+		err = models.InsertPGTransaction(&insert, tx)
+		if err != nil {
+			return err
+		}
 
+		Result, err = PG.CreatePayment(Req, credential)
 		if err != nil {
-			return helper.SendResponse(fiber.StatusInternalServerError, fmt.Sprintf("%v", err), nil, c)
+			return err
 		}
 
-		qrCode, err := helper.GenerateQRCodeBase64(RedirectUrl)
+		err = models.UpdatePGTransactionVendorResult(insert.OrderID, Result.VendorReference, Result.QRString, Result.VendorPayload, tx)
 		if err != nil {
-			return helper.SendResponse(fiber.StatusInternalServerError, "Failed to generate QR code", nil, c)
+			return err
 		}
 
-		return helper.SendResponse(fiber.StatusOK, "", fiber.Map{
-			"redirect_url": RedirectUrl,
-			"qr_code":      qrCode,
-		}, c)
+		return nil
+	})
+
+	if errors.Is(err, models.ErrOrderIDExists) {
+		return helper.SendResponse(fiber.StatusConflict, "order_id already exists", nil, c)
+	}
+	if err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, fmt.Sprintf("%v", err), nil, c)
+	}
+
+	QRContent := Result.QRString
+	if QRContent == "" {
+		QRContent = Result.RedirectURL
+	}
+	qrCode, err := helper.GenerateQRCodeBase64(QRContent)
+	if err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "Failed to generate QR code", nil, c)
 	}
 
-	return helper.SendResponse(fiber.StatusBadRequest, "no vendor code registered yet", nil, c)
+	Response := fiber.Map{
+		"order_id":     Req.OrderID,
+		"redirect_url": Result.RedirectURL,
+		"qr_code":      qrCode,
+	}
+	if Result.QRString != "" {
+		Response["qr_string"] = Result.QRString
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "", Response, c)
 }
 
 func HandleGetPaymentStatus(c *fiber.Ctx) error {
@@ -244,11 +146,11 @@ func HandleGetPaymentStatus(c *fiber.Ctx) error {
 	}
 	StartDate := c.Query("start_date")
 	EndDate := c.Query("end_date")
-	Username := helper.GetUsernameFiber(c)
+	Organization := helper.GetOrganizationFiber(c)
 
 	var transactions []db_var.PaymentGatewayTransactionT
 	db := global_var.DB.Model(&db_var.PaymentGatewayTransactionT{}).
-		Where("vendor = ? AND user_code = ?", VendorCode, Username)
+		Where("vendor = ? AND organization_code = ?", VendorCode, Organization)
 
 	if len(orderIDList) > 0 {
 		db = db.Where("order_id IN ?", orderIDList)
@@ -279,15 +181,50 @@ func HandleGetPaymentStatus(c *fiber.Ctx) error {
 
 	var DataReturn []DataReturnStruct
 	for _, v := range transactions {
+		PaidAt := ""
+		if v.PaidAt != nil {
+			PaidAt = v.PaidAt.Format("2006-01-02")
+		}
 		DataReturn = append(DataReturn, DataReturnStruct{
 			OrderID:        v.OrderID,
 			Amount:         float64(v.Amount),
 			PaymentMethods: v.PaymentMethods,
 			Status:         v.Status,
-			PaidAt:         v.PaidAt.Format("2006-01-02"),
+			PaidAt:         PaidAt,
 			CreatedAt:      v.CreatedAt.Format("2006-01-02"),
 		})
 	}
 
 	return helper.SendResponse(fiber.StatusOK, "", DataReturn, c)
 }
+
+// GetTransactionDetail returns a transaction of the caller's organization with
+// its status history and refunds.
+func GetTransactionDetail(c *fiber.Ctx) error {
+	OrderID := c.Params("order_id")
+	Organization := helper.GetOrganizationFiber(c)
+
+	var transaction db_var.PaymentGatewayTransactionT
+	if err := global_var.DB.Where("order_id = ? AND organization_code = ?", OrderID, Organization).First(&transaction).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return helper.SendResponse(fiber.StatusBadRequest, "Transaction not found", nil, c)
+		}
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	var events []db_var.TransactionEventT
+	if err := global_var.DB.Where("transaction_id = ?", transaction.ID).Order("id").Find(&events).Error; err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	var refunds []db_var.RefundT
+	if err := global_var.DB.Where("order_id = ? AND organization_code = ?", transaction.OrderID, Organization).Order("id").Find(&refunds).Error; err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "", fiber.Map{
+		"transaction": transaction,
+		"events":      events,
+		"refunds":     refunds,
+	}, c)
+}
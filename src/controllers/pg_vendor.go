@@ -6,6 +6,8 @@ import (
 	"pg_bridge_go/db_var"
 	"pg_bridge_go/global_var"
 	"pg_bridge_go/helper"
+	"pg_bridge_go/provider"
+	"slices"
 	"strings"
 	"time"
 
@@ -23,6 +25,9 @@ func CreatePaymentGatewayCredential(c *fiber.Ctx) error {
 		CallbackURL      string `json:"callback_url"`
 		CallbackRedirect int    `json:"callback_redirect"`
 		Mode             string `json:"mode"`
+		CheckoutMode     string `json:"checkout_mode"`
+		OrderIDPrefix    string `json:"order_id_prefix"`
+		OrderIDTemplate  string `json:"order_id_template"`
 	}
 
 	var input Request
@@ -46,6 +51,15 @@ func CreatePaymentGatewayCredential(c *fiber.Ctx) error {
 		return helper.SendResponse(fiber.StatusBadRequest, "Invalid vendor", nil, c)
 	}
 
+	if !validCheckoutMode(input.CheckoutMode) {
+		return helper.SendResponse(fiber.StatusBadRequest, "Invalid checkout mode", nil, c)
+	}
+
+	// The code is only known after insert, so check with the widest one we hand out
+	if err := validOrderIDSettings(prefix, prefix+"-9999999999", input.OrderIDPrefix, input.OrderIDTemplate); err != nil {
+		return helper.SendResponse(fiber.StatusBadRequest, err.Error(), nil, c)
+	}
+
 	ApiKeyEn, err := helper.Encrypt(input.APIKey, config.MasterKey)
 	if err != nil {
 		return helper.SendResponse(fiber.StatusInternalServerError, fmt.Sprintf("%v", err), nil, c)
@@ -58,6 +72,14 @@ func CreatePaymentGatewayCredential(c *fiber.Ctx) error {
 	if err != nil {
 		return helper.SendResponse(fiber.StatusInternalServerError, fmt.Sprintf("%v", err), nil, c)
 	}
+	WebhookSecret, err := helper.GenerateSigningSecret()
+	if err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "Failed to generate webhook secret", nil, c)
+	}
+	WebhookSecretEn, err := helper.Encrypt(WebhookSecret, config.MasterKey)
+	if err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, fmt.Sprintf("%v", err), nil, c)
+	}
 
 	credential := db_var.PaymentGatewayCredentialT{
 		GatewayName:      input.GatewayName,
@@ -67,7 +89,12 @@ func CreatePaymentGatewayCredential(c *fiber.Ctx) error {
 		CallbackURL:      input.CallbackURL,
 		CallbackRedirect: input.CallbackRedirect,
 		Mode:             input.Mode,
+		CheckoutMode:     input.CheckoutMode,
+		OrderIDPrefix:    input.OrderIDPrefix,
+		OrderIDTemplate:  input.OrderIDTemplate,
+		WebhookSecret:    WebhookSecretEn,
 		UserCode:         helper.GetUsernameFiber(c),
+		OrganizationCode: helper.GetOrganizationFiber(c),
 		CreatedBy:        helper.GetUsernameFiber(c),
 	}
 
@@ -83,6 +110,7 @@ func CreatePaymentGatewayCredential(c *fiber.Ctx) error {
 	}
 
 	credential.Code = code
+	credential.WebhookSecret = WebhookSecret
 
 	return helper.SendResponse(fiber.StatusOK, "", credential, c)
 }
@@ -91,33 +119,30 @@ func GetPaymentGatewayCredential(c *fiber.Ctx) error {
 	code := c.Params("code")
 
 	var credential db_var.PaymentGatewayCredentialT
-	if err := global_var.DB.Where("code = ? AND user_code = ?", code, helper.GetUsernameFiber(c)).First(&credential).Error; err != nil {
+	if err := global_var.DB.Where("code = ? AND organization_code = ?", code, helper.GetOrganizationFiber(c)).First(&credential).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return helper.SendResponse(fiber.StatusBadRequest, "Credential not found", nil, c)
 		}
 		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
 	}
 
-	credential.APIKey, _ = helper.Decrypt(credential.APIKey, config.MasterKey)
-	credential.APISecret, _ = helper.Decrypt(credential.APISecret, config.MasterKey)
-	credential.MerchantID, _ = helper.Decrypt(credential.MerchantID, config.MasterKey)
+	revealCredentialSecrets(&credential, canReadCredentialSecrets(c))
 
 	return helper.SendResponse(fiber.StatusOK, "", credential, c)
 }
 
 func GetAllPaymentGatewayCredential(c *fiber.Ctx) error {
 	var credential []db_var.PaymentGatewayCredentialT
-	if err := global_var.DB.Where("user_code = ?", helper.GetUsernameFiber(c)).Find(&credential).Error; err != nil {
+	if err := global_var.DB.Where("organization_code = ?", helper.GetOrganizationFiber(c)).Find(&credential).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return helper.SendResponse(fiber.StatusBadRequest, "Credential not found", nil, c)
 		}
 		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
 	}
 
+	Secrets := canReadCredentialSecrets(c)
 	for i := range credential {
-		credential[i].APIKey, _ = helper.Decrypt(credential[i].APIKey, config.MasterKey)
-		credential[i].APISecret, _ = helper.Decrypt(credential[i].APISecret, config.MasterKey)
-		credential[i].MerchantID, _ = helper.Decrypt(credential[i].MerchantID, config.MasterKey)
+		revealCredentialSecrets(&credential[i], Secrets)
 	}
 
 	return helper.SendResponse(fiber.StatusOK, "", credential, c)
@@ -132,12 +157,15 @@ func UpdatePaymentGatewayCredential(c *fiber.Ctx) error {
 		CallbackURL      string `json:"callback_url"`
 		CallbackRedirect int    `json:"callback_redirect"`
 		Mode             string `json:"mode"`
+		CheckoutMode     string `json:"checkout_mode"`
+		OrderIDPrefix    string `json:"order_id_prefix"`
+		OrderIDTemplate  string `json:"order_id_template"`
 	}
 
 	code := c.Params("code")
 
 	var credential db_var.PaymentGatewayCredentialT
-	if err := global_var.DB.Where("code = ? AND user_code = ?", code, helper.GetUsernameFiber(c)).First(&credential).Error; err != nil {
+	if err := global_var.DB.Where("code = ? AND organization_code = ?", code, helper.GetOrganizationFiber(c)).First(&credential).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return helper.SendResponse(fiber.StatusBadRequest, "Credential not found", nil, c)
 		}
@@ -149,6 +177,14 @@ func UpdatePaymentGatewayCredential(c *fiber.Ctx) error {
 		return helper.SendResponse(fiber.StatusBadRequest, nil, nil, c)
 	}
 
+	if !validCheckoutMode(input.CheckoutMode) {
+		return helper.SendResponse(fiber.StatusBadRequest, "Invalid checkout mode", nil, c)
+	}
+
+	if err := validOrderIDSettings(provider.VendorPrefix(credential.Code), credential.Code, input.OrderIDPrefix, input.OrderIDTemplate); err != nil {
+		return helper.SendResponse(fiber.StatusBadRequest, err.Error(), nil, c)
+	}
+
 	credential.GatewayName = input.GatewayName
 	credential.APIKey, _ = helper.Encrypt(input.APIKey, config.MasterKey)
 	credential.APISecret, _ = helper.Encrypt(input.APISecret, config.MasterKey)
@@ -156,22 +192,150 @@ func UpdatePaymentGatewayCredential(c *fiber.Ctx) error {
 	credential.CallbackURL = input.CallbackURL
 	credential.CallbackRedirect = input.CallbackRedirect
 	credential.Mode = input.Mode
+	credential.CheckoutMode = input.CheckoutMode
+	credential.OrderIDPrefix = input.OrderIDPrefix
+	credential.OrderIDTemplate = input.OrderIDTemplate
 	credential.UpdatedAt = time.Now()
 	credential.UpdatedBy = helper.GetUsernameFiber(c)
 
-	if err := global_var.DB.Save(&credential).Error; err != nil {
+	// The counter moves on its own while the credential is being edited
+	if err := global_var.DB.Omit("duplicate_notifications").Save(&credential).Error; err != nil {
 		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
 	}
 
+	revealCredentialSecrets(&credential, true)
+
 	return helper.SendResponse(fiber.StatusOK, "", credential, c)
 }
 
+// canReadCredentialSecrets reports whether the caller may see the decrypted
+// vendor keys of a credential.
+func canReadCredentialSecrets(c *fiber.Ctx) bool {
+	Scopes := helper.GetScopesFiber(c)
+	return slices.Contains(Scopes, global_var.ScopeCredentialsReadSecrets) || slices.Contains(Scopes, global_var.ScopeCredentialsManage)
+}
+
+// revealCredentialSecrets prepares a stored credential for a response. The
+// vendor keys are decrypted when secrets is set and left out otherwise; the
+// webhook signing secret is only handed out on create and rotate.
+func revealCredentialSecrets(credential *db_var.PaymentGatewayCredentialT, secrets bool) {
+	if secrets {
+		credential.APIKey, _ = helper.Decrypt(credential.APIKey, config.MasterKey)
+		credential.APISecret, _ = helper.Decrypt(credential.APISecret, config.MasterKey)
+		credential.MerchantID, _ = helper.Decrypt(credential.MerchantID, config.MasterKey)
+	} else {
+		credential.APIKey, credential.APISecret, credential.MerchantID = "", "", ""
+	}
+	credential.WebhookSecret = ""
+}
+
+// RotateWebhookSecret issues a new webhook signing secret. The old secret keeps
+// signing alongside the new one for the grace period so receivers can switch
+// over without dropping webhooks.
+func RotateWebhookSecret(c *fiber.Ctx) error {
+	type Request struct {
+		GracePeriodHours *int `json:"grace_period_hours"`
+	}
+
+	var input Request
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&input); err != nil {
+			return helper.SendResponse(fiber.StatusBadRequest, nil, nil, c)
+		}
+	}
+
+	GracePeriodHours := 24
+	if input.GracePeriodHours != nil {
+		GracePeriodHours = *input.GracePeriodHours
+	}
+	if GracePeriodHours < 0 || GracePeriodHours > 168 {
+		return helper.SendResponse(fiber.StatusBadRequest, "grace_period_hours must be between 0 and 168", nil, c)
+	}
+
+	code := c.Params("code")
+
+	var credential db_var.PaymentGatewayCredentialT
+	if err := global_var.DB.Where("code = ? AND organization_code = ?", code, helper.GetOrganizationFiber(c)).First(&credential).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return helper.SendResponse(fiber.StatusBadRequest, "Credential not found", nil, c)
+		}
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	WebhookSecret, err := helper.GenerateSigningSecret()
+	if err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "Failed to generate webhook secret", nil, c)
+	}
+	WebhookSecretEn, err := helper.Encrypt(WebhookSecret, config.MasterKey)
+	if err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, fmt.Sprintf("%v", err), nil, c)
+	}
+
+	updates := map[string]interface{}{
+		"webhook_secret":                     WebhookSecretEn,
+		"webhook_secret_previous":            "",
+		"webhook_secret_previous_expires_at": nil,
+		"updated_at":                         time.Now(),
+		"updated_by":                         helper.GetUsernameFiber(c),
+	}
+	var PreviousExpiresAt *time.Time
+	if credential.WebhookSecret != "" && GracePeriodHours > 0 {
+		t := time.Now().Add(time.Duration(GracePeriodHours) * time.Hour)
+		PreviousExpiresAt = &t
+		updates["webhook_secret_previous"] = credential.WebhookSecret
+		updates["webhook_secret_previous_expires_at"] = PreviousExpiresAt
+	}
+
+	if err := global_var.DB.Model(&credential).Updates(updates).Error; err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "Webhook secret rotated", fiber.Map{
+		"code":                               credential.Code,
+		"webhook_secret":                     WebhookSecret,
+		"webhook_secret_previous_expires_at": PreviousExpiresAt,
+	}, c)
+}
+
 func DeletePaymentGatewayCredential(c *fiber.Ctx) error {
 	code := c.Params("code")
 
-	if err := global_var.DB.Where("code = ? AND user_code = ?", code, helper.GetUsernameFiber(c)).Delete(&db_var.PaymentGatewayCredentialT{}).Error; err != nil {
+	if err := global_var.DB.Where("code = ? AND organization_code = ?", code, helper.GetOrganizationFiber(c)).Delete(&db_var.PaymentGatewayCredentialT{}).Error; err != nil {
 		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
 	}
 
 	return helper.SendResponse(fiber.StatusOK, "Credential deleted", nil, c)
 }
+
+// validOrderIDSettings renders a sample order id from the template and checks
+// it against the vendor order id rules.
+func validOrderIDSettings(vendorPrefix, vendorCode, orderIDPrefix, template string) error {
+	if template != "" {
+		if err := helper.ValidateOrderIDTemplate(template); err != nil {
+			return err
+		}
+	}
+
+	PG, ok := provider.Get(vendorPrefix)
+	if !ok {
+		// Vendors without an adapter cannot create payments yet
+		return nil
+	}
+
+	// A twelve digit sequence leaves room for years of growth
+	sample, err := helper.GenerateOrderID(template, orderIDPrefix, vendorCode, time.Now(), func() (int64, error) {
+		return 999999999999, nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := PG.OrderIDRules().Check(sample); err != nil {
+		return fmt.Errorf("order id template produces %q: %w", sample, err)
+	}
+	return nil
+}
+
+// validCheckoutMode reports whether mode is empty (vendor default) or a known checkout mode
+func validCheckoutMode(mode string) bool {
+	return mode == "" || mode == global_var.CheckoutModeQR || mode == global_var.CheckoutModeInvoice
+}
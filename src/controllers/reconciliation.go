@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"errors"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/helper"
+	"pg_bridge_go/jobs"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// StartReconciliationRun starts a reconciliation now instead of waiting for
+// the schedule. The run continues in the background.
+func StartReconciliationRun(c *fiber.Ctx) error {
+	run, err := jobs.StartReconcile("manual", helper.GetUsernameFiber(c))
+	if errors.Is(err, jobs.ErrReconcileRunning) {
+		return helper.SendResponse(fiber.StatusConflict, err.Error(), nil, c)
+	}
+	if err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusAccepted, "Reconciliation started", run, c)
+}
+
+func GetAllReconciliationRun(c *fiber.Ctx) error {
+	Limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil || Limit < 1 || Limit > 200 {
+		return helper.SendResponse(fiber.StatusBadRequest, "limit must be between 1 and 200", nil, c)
+	}
+
+	var runs []db_var.ReconciliationRunT
+	if err := global_var.DB.Order("id desc").Limit(Limit).Find(&runs).Error; err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "", runs, c)
+}
+
+func GetReconciliationRun(c *fiber.Ctx) error {
+	ID, err := c.ParamsInt("id")
+	if err != nil {
+		return helper.SendResponse(fiber.StatusBadRequest, "Invalid run id", nil, c)
+	}
+
+	var run db_var.ReconciliationRunT
+	if err := global_var.DB.Where("id = ?", ID).First(&run).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return helper.SendResponse(fiber.StatusBadRequest, "Run not found", nil, c)
+		}
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	var items []db_var.ReconciliationItemT
+	if err := global_var.DB.Where("run_id = ?", run.ID).Order("id").Find(&items).Error; err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "", fiber.Map{
+		"run":   run,
+		"items": items,
+	}, c)
+}
+
+// GetAllReconciliationItem lists the discrepancies found for the caller's organization
+func GetAllReconciliationItem(c *fiber.Ctx) error {
+	Limit, err := strconv.Atoi(c.Query("limit", "50"))
+	if err != nil || Limit < 1 || Limit > 200 {
+		return helper.SendResponse(fiber.StatusBadRequest, "limit must be between 1 and 200", nil, c)
+	}
+
+	db := global_var.DB.Model(&db_var.ReconciliationItemT{}).
+		Where("organization_code = ?", helper.GetOrganizationFiber(c))
+
+	if RunID := c.Query("run_id"); RunID != "" {
+		db = db.Where("run_id = ?", RunID)
+	}
+	if OrderID := c.Query("order_id"); OrderID != "" {
+		db = db.Where("order_id = ?", OrderID)
+	}
+	if Action := c.Query("action"); Action != "" {
+		db = db.Where("action = ?", Action)
+	}
+
+	var items []db_var.ReconciliationItemT
+	if err := db.Order("id desc").Limit(Limit).Find(&items).Error; err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "", items, c)
+}
@@ -0,0 +1,242 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/helper"
+	"pg_bridge_go/logger"
+	"pg_bridge_go/models"
+	"pg_bridge_go/provider"
+	"pg_bridge_go/webhook"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RefundRequestBody struct {
+	OrderID string `json:"order_id"`
+	// Amount of zero refunds everything that is left.
+	Amount int    `json:"amount"`
+	Reason string `json:"reason"`
+}
+
+// notRefundableTxStatuses are the stored statuses of transactions that were
+// never captured or have nothing left to refund.
+var notRefundableTxStatuses = []string{
+	global_var.TxStatusPending,
+	global_var.TxStatusSent,
+	global_var.TxStatusWaitingPayment,
+	global_var.TxStatusExpired,
+	global_var.TxStatusFailed,
+	global_var.TxStatusCancelled,
+	global_var.TxStatusError,
+	global_var.TxStatusRefunded,
+}
+
+// reservedRefundStatuses count against the refundable amount.
+var reservedRefundStatuses = []string{global_var.RefundStatusPending, global_var.RefundStatusSucceeded}
+
+// HandleRefund refunds all or part of a paid transaction. The refund is stored
+// as pending before the vendor is called so concurrent refunds of the same
+// order can never add up to more than the captured amount. It is only marked
+// failed when the vendor definitely refused it.
+func HandleRefund(c *fiber.Ctx) error {
+	VendorCode := c.Params("vendorcode")
+	Organization := helper.GetOrganizationFiber(c)
+	Username := helper.GetUsernameFiber(c)
+	var Req RefundRequestBody
+
+	if err := c.BodyParser(&Req); err != nil {
+		return helper.SendResponse(fiber.StatusBadRequest, nil, nil, c)
+	}
+	Req.OrderID = strings.TrimSpace(Req.OrderID)
+	if Req.OrderID == "" {
+		return helper.SendResponse(fiber.StatusBadRequest, "order_id is required", nil, c)
+	}
+	if Req.Amount < 0 {
+		return helper.SendResponse(fiber.StatusBadRequest, "amount must not be negative", nil, c)
+	}
+	if len(Req.Reason) > 255 {
+		return helper.SendResponse(fiber.StatusBadRequest, "reason must be at most 255 characters", nil, c)
+	}
+
+	var credential db_var.PaymentGatewayCredentialT
+	if err := global_var.DB.Where("code = ? AND organization_code = ?", VendorCode, Organization).First(&credential).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return helper.SendResponse(fiber.StatusBadRequest, "Credential not found", nil, c)
+		}
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	PG, ok := provider.ForVendorCode(VendorCode)
+	if !ok {
+		return helper.SendResponse(fiber.StatusBadRequest, "no vendor code registered yet", nil, c)
+	}
+
+	RefundID, err := helper.NewULID(time.Now())
+	if err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	refund := db_var.RefundT{
+		RefundID:         "RF-" + RefundID,
+		OrderID:          Req.OrderID,
+		OrganizationCode: Organization,
+		VendorCode:       VendorCode,
+		Amount:           Req.Amount,
+		Reason:           Req.Reason,
+		Status:           global_var.RefundStatusPending,
+		CreatedBy:        Username,
+		UpdatedBy:        Username,
+	}
+
+	var Rejected string
+	err = global_var.DB.Transaction(func(tx *gorm.DB) error {
+		var transaction db_var.PaymentGatewayTransactionT
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id = ? AND vendor = ? AND organization_code = ?", Req.OrderID, VendorCode, Organization).
+			First(&transaction).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			Rejected = "Transaction not found"
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if slices.Contains(notRefundableTxStatuses, transaction.Status) {
+			Rejected = "transaction with status " + transaction.Status + " cannot be refunded"
+			return nil
+		}
+
+		Refunded, err := models.RefundedAmount(transaction.OrderID, reservedRefundStatuses, tx)
+		if err != nil {
+			return err
+		}
+		Remaining := transaction.Amount - Refunded
+		if refund.Amount == 0 {
+			refund.Amount = Remaining
+		}
+		if Remaining <= 0 {
+			Rejected = "transaction has nothing left to refund"
+			return nil
+		}
+		if refund.Amount > Remaining {
+			Rejected = fmt.Sprintf("amount exceeds the refundable amount of %d", Remaining)
+			return nil
+		}
+
+		return models.InsertRefund(&refund, tx)
+	})
+	if err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+	if Rejected != "" {
+		return helper.SendResponse(fiber.StatusBadRequest, Rejected, nil, c)
+	}
+
+	Result, err := PG.Refund(provider.RefundRequest{
+		OrderID:  refund.OrderID,
+		RefundID: refund.RefundID,
+		Amount:   refund.Amount,
+		Reason:   refund.Reason,
+	}, credential)
+	if err != nil && !errors.Is(err, provider.ErrNotSupported) && !errors.Is(err, provider.ErrVendorRejected) {
+		// The vendor may have refunded anyway; leaving the refund pending keeps the amount reserved
+		logger.Error("Refund outcome unknown", zap.String("refund_id", refund.RefundID), zap.Error(err))
+		return helper.SendResponse(fiber.StatusAccepted, "refund outcome is unknown; it stays pending until confirmed with the vendor", refund, c)
+	}
+	if err != nil {
+		refund.Status = global_var.RefundStatusFailed
+		refund.FailureReason = err.Error()
+		if err := models.UpdateRefundResult(refund.RefundID, refund.Status, "", "", refund.FailureReason, nil, Username, global_var.DB); err != nil {
+			logger.Error("Failed to record refund failure", zap.String("refund_id", refund.RefundID), zap.Error(err))
+		}
+		if errors.Is(err, provider.ErrNotSupported) {
+			return helper.SendResponse(fiber.StatusBadRequest, "refunds are not supported for this vendor", refund, c)
+		}
+		return helper.SendResponse(fiber.StatusBadGateway, err.Error(), refund, c)
+	}
+
+	refund.Status = global_var.RefundStatusSucceeded
+	refund.VendorReference = Result.VendorReference
+	refund.VendorStatus = Result.VendorStatus
+
+	err = global_var.DB.Transaction(func(tx *gorm.DB) error {
+		if err := models.UpdateRefundResult(refund.RefundID, refund.Status, refund.VendorReference, refund.VendorStatus, "", Result.Raw, Username, tx); err != nil {
+			return err
+		}
+
+		var transaction db_var.PaymentGatewayTransactionT
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", refund.OrderID).First(&transaction).Error; err != nil {
+			return err
+		}
+
+		Refunded, err := models.RefundedAmount(transaction.OrderID, []string{global_var.RefundStatusSucceeded}, tx)
+		if err != nil {
+			return err
+		}
+		NewStatus := global_var.TxStatusPartiallyRefunded
+		if Refunded >= transaction.Amount {
+			NewStatus = global_var.TxStatusRefunded
+		}
+		if transaction.Status == NewStatus {
+			return nil
+		}
+
+		if _, err := models.UpdatePGTransactionStatus(transaction.OrderID, NewStatus, "", Username, models.StatusCause{
+			Source:       global_var.TxEventSourceManual,
+			VendorStatus: Result.VendorStatus,
+			PayloadRef:   "refund:" + refund.RefundID,
+		}, tx); err != nil {
+			return err
+		}
+
+		return webhook.Enqueue(credential, webhook.NewStatusChangeEvent(transaction, &provider.TransactionStatus{
+			OrderID:      transaction.OrderID,
+			VendorStatus: Result.VendorStatus,
+			Status:       NewStatus,
+			PaymentType:  transaction.PaymentMethods,
+		}, time.Now()), tx)
+	})
+	if err != nil {
+		// The vendor has refunded; leaving the refund pending keeps the amount reserved
+		logger.Error("Failed to record refund result", zap.String("refund_id", refund.RefundID), zap.Error(err))
+		return helper.SendResponse(fiber.StatusInternalServerError, "Refund was accepted by the vendor but could not be recorded", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "", refund, c)
+}
+
+// GetAllRefund lists the refunds made through a credential of the caller's organization
+func GetAllRefund(c *fiber.Ctx) error {
+	Limit, err := strconv.Atoi(c.Query("limit", "50"))
+	if err != nil || Limit < 1 || Limit > 200 {
+		return helper.SendResponse(fiber.StatusBadRequest, "limit must be between 1 and 200", nil, c)
+	}
+
+	db := global_var.DB.Model(&db_var.RefundT{}).
+		Where("vendor_code = ? AND organization_code = ?", c.Params("vendorcode"), helper.GetOrganizationFiber(c))
+
+	if OrderID := c.Query("order_id"); OrderID != "" {
+		db = db.Where("order_id = ?", OrderID)
+	}
+	if Status := c.Query("status"); Status != "" {
+		db = db.Where("status = ?", Status)
+	}
+
+	var refunds []db_var.RefundT
+	if err := db.Order("id desc").Limit(Limit).Find(&refunds).Error; err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "", refunds, c)
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"errors"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/helper"
+	"pg_bridge_go/settlement"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// ImportSettlementFile matches an uploaded vendor settlement CSV against the
+// credential transactions and returns the stored report.
+func ImportSettlementFile(c *fiber.Ctx) error {
+	VendorCode := c.Params("vendorcode")
+
+	var credential db_var.PaymentGatewayCredentialT
+	if err := global_var.DB.Where("code = ? AND organization_code = ?", VendorCode, helper.GetOrganizationFiber(c)).First(&credential).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return helper.SendResponse(fiber.StatusBadRequest, "Credential not found", nil, c)
+		}
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	start, end, err := settlement.ParsePeriod(c.FormValue("start_date"), c.FormValue("end_date"))
+	if err != nil {
+		return helper.SendResponse(fiber.StatusBadRequest, err.Error(), nil, c)
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return helper.SendResponse(fiber.StatusBadRequest, "file is required", nil, c)
+	}
+	file, err := header.Open()
+	if err != nil {
+		return helper.SendResponse(fiber.StatusBadRequest, "Failed to read file", nil, c)
+	}
+	defer file.Close()
+
+	record, err := settlement.Import(global_var.DB, credential, header.Filename, file, start, end, helper.GetUsernameFiber(c))
+	if err != nil {
+		return helper.SendResponse(fiber.StatusBadRequest, err.Error(), nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "", record, c)
+}
+
+func GetAllSettlementImport(c *fiber.Ctx) error {
+	Limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil || Limit < 1 || Limit > 200 {
+		return helper.SendResponse(fiber.StatusBadRequest, "limit must be between 1 and 200", nil, c)
+	}
+
+	db := global_var.DB.Omit("items").Where("organization_code = ?", helper.GetOrganizationFiber(c))
+	if VendorCode := c.Query("vendor_code"); VendorCode != "" {
+		db = db.Where("vendor_code = ?", VendorCode)
+	}
+
+	var imports []db_var.SettlementImportT
+	if err := db.Order("id desc").Limit(Limit).Find(&imports).Error; err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "", imports, c)
+}
+
+func GetSettlementImport(c *fiber.Ctx) error {
+	ID, err := c.ParamsInt("id")
+	if err != nil {
+		return helper.SendResponse(fiber.StatusBadRequest, "Invalid import id", nil, c)
+	}
+
+	var record db_var.SettlementImportT
+	if err := global_var.DB.Where("id = ? AND organization_code = ?", ID, helper.GetOrganizationFiber(c)).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return helper.SendResponse(fiber.StatusBadRequest, "Import not found", nil, c)
+		}
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "", record, c)
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/helper"
+	"pg_bridge_go/webhook"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func GetAllWebhookDelivery(c *fiber.Ctx) error {
+	Limit, err := strconv.Atoi(c.Query("limit", "50"))
+	if err != nil || Limit < 1 || Limit > 200 {
+		return helper.SendResponse(fiber.StatusBadRequest, "limit must be between 1 and 200", nil, c)
+	}
+
+	db := global_var.DB.Model(&db_var.WebhookDeliveryT{}).
+		Where("organization_code = ?", helper.GetOrganizationFiber(c))
+
+	if Status := c.Query("status"); Status != "" {
+		db = db.Where("status = ?", Status)
+	}
+	if OrderID := c.Query("order_id"); OrderID != "" {
+		db = db.Where("order_id = ?", OrderID)
+	}
+	if VendorCode := c.Query("vendor_code"); VendorCode != "" {
+		db = db.Where("vendor_code = ?", VendorCode)
+	}
+
+	var deliveries []db_var.WebhookDeliveryT
+	if err := db.Order("created_at desc").Limit(Limit).Find(&deliveries).Error; err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, err.Error(), nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "", deliveries, c)
+}
+
+func GetWebhookDelivery(c *fiber.Ctx) error {
+	delivery, status, message := findWebhookDelivery(c)
+	if delivery == nil {
+		return helper.SendResponse(status, message, nil, c)
+	}
+
+	var attempts []db_var.WebhookAttemptT
+	if err := global_var.DB.Where("delivery_id = ?", delivery.ID).Order("id").Find(&attempts).Error; err != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "", fiber.Map{
+		"delivery": delivery,
+		"attempts": attempts,
+	}, c)
+}
+
+// RedeliverWebhook puts a delivered or dead-lettered delivery back in the queue
+// with a fresh set of attempts.
+func RedeliverWebhook(c *fiber.Ctx) error {
+	delivery, status, message := findWebhookDelivery(c)
+	if delivery == nil {
+		return helper.SendResponse(status, message, nil, c)
+	}
+
+	if delivery.Status == global_var.WebhookStatusPending {
+		return helper.SendResponse(fiber.StatusConflict, "Delivery is already queued", nil, c)
+	}
+
+	result := global_var.DB.Model(&db_var.WebhookDeliveryT{}).
+		Where("id = ? AND status = ?", delivery.ID, delivery.Status).
+		Updates(map[string]interface{}{
+			"status":          global_var.WebhookStatusPending,
+			"max_attempts":    delivery.Attempts + webhook.MaxAttempts,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+	}
+	if result.RowsAffected == 0 {
+		return helper.SendResponse(fiber.StatusConflict, "Delivery is already queued", nil, c)
+	}
+
+	return helper.SendResponse(fiber.StatusOK, "Delivery queued", fiber.Map{"id": delivery.ID}, c)
+}
+
+// findWebhookDelivery loads the :id delivery owned by the caller's organization
+func findWebhookDelivery(c *fiber.Ctx) (*db_var.WebhookDeliveryT, int, string) {
+	ID, err := c.ParamsInt("id")
+	if err != nil {
+		return nil, fiber.StatusBadRequest, "Invalid delivery id"
+	}
+
+	var delivery db_var.WebhookDeliveryT
+	if err := global_var.DB.Where("id = ? AND organization_code = ?", ID, helper.GetOrganizationFiber(c)).First(&delivery).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fiber.StatusBadRequest, "Delivery not found"
+		}
+		return nil, fiber.StatusInternalServerError, ""
+	}
+	return &delivery, 0, ""
+}
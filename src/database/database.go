@@ -7,6 +7,7 @@ import (
 	"pg_bridge_go/config"
 	"pg_bridge_go/db_var"
 	"pg_bridge_go/global_var"
+	"pg_bridge_go/models"
 	"time"
 
 	loggers "pg_bridge_go/logger"
@@ -17,8 +18,9 @@ import (
 	"gorm.io/gorm/logger"
 )
 
-// SetupDatabase migrates and sets up the database.
-func SetupDatabase() {
+// Open connects to the database from the env config without migrating it,
+// logging SQL at logLevel.
+func Open(logLevel logger.LogLevel) (*gorm.DB, error) {
 	var credentials global_var.DatabaseConnection = config.GetEnvDatabase()
 	u := credentials.User
 	p := credentials.Password
@@ -29,7 +31,7 @@ func SetupDatabase() {
 		log.New(os.Stdout, "\r\n", log.LstdFlags),
 		logger.Config{
 			SlowThreshold: time.Second,
-			LogLevel:      logger.Info,
+			LogLevel:      logLevel,
 			Colorful:      true,
 		},
 	)
@@ -37,23 +39,80 @@ func SetupDatabase() {
 	// PostgreSQL DSN format: host=localhost user=gorm password=gorm dbname=gorm port=9920 sslmode=disable TimeZone=Asia/Shanghai
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Shanghai", credentials.Host, u, p, n, credentials.Port)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	return gorm.Open(postgres.Open(dsn), &gorm.Config{
 		Logger: stdoutLogger,
 	})
+}
+
+// SetupDatabase migrates and sets up the database.
+func SetupDatabase() {
+	db, err := Open(logger.Info)
 	if err != nil {
 		loggers.Error("Could not connect to database", zap.Error(err))
 		log.Panic("Could not connect to database:", err)
 	}
 
+	// Must run before AutoMigrate adds the canonical status unique index
+	err = models.MigrateProcessedNotificationKey(db)
+	if err != nil {
+		loggers.Error("Error migrating processed notifications", zap.Error(err))
+		log.Panic("Error migrating processed notifications:", err)
+	}
+
+	err = models.MigrateNotificationInboxHeaders(db)
+	if err != nil {
+		loggers.Error("Error redacting stored notification headers", zap.Error(err))
+		log.Panic("Error redacting stored notification headers:", err)
+	}
+
 	err = db.Debug().AutoMigrate(
 		&db_var.UserT{},
 		&db_var.PaymentGatewayCredentialT{},
 		&db_var.PaymentGatewayTransactionT{},
+		&db_var.APIKeyT{},
+		&db_var.RequestNonceT{},
+		&db_var.OrganizationT{},
+		&db_var.OrganizationMemberT{},
+		&db_var.WebhookDeliveryT{},
+		&db_var.WebhookAttemptT{},
+		&db_var.IdempotencyKeyT{},
+		&db_var.ReconciliationRunT{},
+		&db_var.ReconciliationItemT{},
+		&db_var.SettlementImportT{},
+		&db_var.RefundT{},
+		&db_var.TransactionEventT{},
+		&db_var.NotificationInboxT{},
+		&db_var.ProcessedNotificationT{},
 	)
 	if err != nil {
 		loggers.Error("Error during migration", zap.Error(err))
 		log.Panic("Error during migration:", err)
 	}
 
+	err = models.CreateOrderIDSequence(db)
+	if err != nil {
+		loggers.Error("Error creating order id sequence", zap.Error(err))
+		log.Panic("Error creating order id sequence:", err)
+	}
+
+	// Move legacy user_code ownership into per-user organizations
+	err = models.MigrateUserCodeOwnership(db)
+	if err != nil {
+		loggers.Error("Error during organization migration", zap.Error(err))
+		log.Panic("Error during organization migration:", err)
+	}
+
+	err = models.BackfillWebhookSecrets(db, config.MasterKey)
+	if err != nil {
+		loggers.Error("Error backfilling webhook secrets", zap.Error(err))
+		log.Panic("Error backfilling webhook secrets:", err)
+	}
+
+	err = models.NormalizePGTransactionStatuses(db)
+	if err != nil {
+		loggers.Error("Error normalizing transaction statuses", zap.Error(err))
+		log.Panic("Error normalizing transaction statuses:", err)
+	}
+
 	global_var.DB = db.Debug()
 }
@@ -12,6 +12,7 @@ type UserT struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	Username  string    `json:"username" gorm:"type:varchar(30);unique"`
 	Password  string    `json:"password" gorm:"type:varchar(200)"`
+	Role      string    `json:"role" gorm:"type:varchar(30);default:'merchant_owner'"`
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 }
 
@@ -19,21 +20,32 @@ func (UserT) TableName() string {
 	return TableName.User
 }
 
+// PaymentGatewayCredentialT holds a vendor account. WebhookSecret signs the
+// outbound merchant webhooks; after a rotation the previous secret keeps
+// signing too until WebhookSecretPreviousExpiresAt.
 type PaymentGatewayCredentialT struct {
-	ID               uint      `json:"id" gorm:"primaryKey"`
-	Code             string    `json:"code" gorm:"type:varchar(100);uniqueIndex"`
-	UserCode         string    `json:"user_code" gorm:"type:varchar(50);not null"`
-	GatewayName      string    `json:"gateway_name" gorm:"type:varchar(50);not null"`
-	APIKey           string    `json:"api_key" gorm:"type:varchar(200);not null"`
-	APISecret        string    `json:"api_secret" gorm:"type:varchar(200);not null"`
-	MerchantID       string    `json:"merchant_id" gorm:"type:varchar(100)"`
-	CallbackURL      string    `json:"callback_url" gorm:"type:varchar(200)"`
-	CallbackRedirect int       `json:"callback_redirect" gorm:"default:0"`
-	Mode             string    `json:"mode" gorm:"type:varchar(10);default:'dev'"`
-	CreatedAt        time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
-	CreatedBy        string    `json:"created_by"`
-	UpdatedAt        time.Time `json:"updated_at" gorm:"autoUpdateTime"`
-	UpdatedBy        string    `json:"updated_by"`
+	ID                             uint       `json:"id" gorm:"primaryKey"`
+	Code                           string     `json:"code" gorm:"type:varchar(100);uniqueIndex"`
+	UserCode                       string     `json:"user_code" gorm:"type:varchar(50);not null"`
+	OrganizationCode               string     `json:"organization_code" gorm:"type:varchar(50);index"`
+	GatewayName                    string     `json:"gateway_name" gorm:"type:varchar(50);not null"`
+	APIKey                         string     `json:"api_key" gorm:"type:varchar(200);not null"`
+	APISecret                      string     `json:"api_secret" gorm:"type:varchar(200);not null"`
+	MerchantID                     string     `json:"merchant_id" gorm:"type:varchar(100)"`
+	CallbackURL                    string     `json:"callback_url" gorm:"type:varchar(200)"`
+	CallbackRedirect               int        `json:"callback_redirect" gorm:"default:0"`
+	Mode                           string     `json:"mode" gorm:"type:varchar(10);default:'dev'"`
+	CheckoutMode                   string     `json:"checkout_mode" gorm:"type:varchar(20)"`
+	OrderIDPrefix                  string     `json:"order_id_prefix" gorm:"type:varchar(20)"`
+	OrderIDTemplate                string     `json:"order_id_template" gorm:"type:varchar(100)"`
+	WebhookSecret                  string     `json:"webhook_secret,omitempty" gorm:"type:varchar(200)"`
+	WebhookSecretPrevious          string     `json:"-" gorm:"type:varchar(200)"`
+	WebhookSecretPreviousExpiresAt *time.Time `json:"webhook_secret_previous_expires_at"`
+	DuplicateNotifications         int64      `json:"duplicate_notifications" gorm:"default:0"`
+	CreatedAt                      time.Time  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	CreatedBy                      string     `json:"created_by"`
+	UpdatedAt                      time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+	UpdatedBy                      string     `json:"updated_by"`
 }
 
 func (PaymentGatewayCredentialT) TableName() string {
@@ -41,25 +53,30 @@ func (PaymentGatewayCredentialT) TableName() string {
 }
 
 type PaymentGatewayTransactionT struct {
-	ID             uint64         `json:"id" gorm:"primaryKey"`
-	OrderID        string         `json:"order_id" gorm:"type:varchar(64);uniqueIndex;not null"`
-	UserCode       string         `json:"user_code" gorm:"type:varchar(50);not null"`
-	Amount         int            `json:"amount" gorm:"not null"`
-	CustomerName   string         `json:"customer_name" gorm:"type:varchar(255)"`
-	CustomerEmail  string         `json:"customer_email" gorm:"type:varchar(255)"`
-	CustomerPhone  string         `json:"customer_phone" gorm:"type:varchar(50)"`
-	ItemsJSON      datatypes.JSON `json:"items_json" gorm:"type:jsonb"`
-	PaymentMethods string         `json:"payment_methods"`
-	CustomFields   datatypes.JSON `json:"custom_fields" gorm:"type:jsonb"`
-	Metadata       datatypes.JSON `json:"metadata" gorm:"type:jsonb"`
-	CallbacksJSON  datatypes.JSON `json:"callbacks_json" gorm:"type:jsonb"`
-	ExpiryStart    *time.Time     `json:"expiry_start"`
-	ExpiryUnit     string         `json:"expiry_unit" gorm:"type:varchar(20)"`
-	ExpiryDuration int            `json:"expiry_duration"`
-	Vendor         string         `json:"vendor" gorm:"type:varchar(50)"`
-	VendorPayload  datatypes.JSON `json:"vendor_payload" gorm:"type:jsonb"`
-	Status         string         `json:"status" gorm:"type:varchar(50);default:'pending'"`
-	PaidAt         time.Time      `json:"paid_at"`
+	ID               uint64         `json:"id" gorm:"primaryKey"`
+	OrderID          string         `json:"order_id" gorm:"type:varchar(64);uniqueIndex;not null"`
+	UserCode         string         `json:"user_code" gorm:"type:varchar(50);not null"`
+	OrganizationCode string         `json:"organization_code" gorm:"type:varchar(50);index"`
+	Amount           int            `json:"amount" gorm:"not null"`
+	CustomerName     string         `json:"customer_name" gorm:"type:varchar(255)"`
+	CustomerEmail    string         `json:"customer_email" gorm:"type:varchar(255)"`
+	CustomerPhone    string         `json:"customer_phone" gorm:"type:varchar(50)"`
+	ItemsJSON        datatypes.JSON `json:"items_json" gorm:"type:jsonb"`
+	PaymentMethods   string         `json:"payment_methods"`
+	CustomFields     datatypes.JSON `json:"custom_fields" gorm:"type:jsonb"`
+	Metadata         datatypes.JSON `json:"metadata" gorm:"type:jsonb"`
+	CallbacksJSON    datatypes.JSON `json:"callbacks_json" gorm:"type:jsonb"`
+	ExpiryStart      *time.Time     `json:"expiry_start"`
+	ExpiryUnit       string         `json:"expiry_unit" gorm:"type:varchar(20)"`
+	ExpiryDuration   int            `json:"expiry_duration"`
+	ExpiresAt        *time.Time     `json:"expires_at" gorm:"index"`
+	ReconciledAt     *time.Time     `json:"reconciled_at"`
+	Vendor           string         `json:"vendor" gorm:"type:varchar(50)"`
+	VendorReference  string         `json:"vendor_reference" gorm:"type:varchar(100);index"`
+	VendorPayload    datatypes.JSON `json:"vendor_payload" gorm:"type:jsonb"`
+	QRString         string         `json:"qr_string" gorm:"type:text"`
+	Status           string         `json:"status" gorm:"type:varchar(50);default:'pending'"`
+	PaidAt           *time.Time     `json:"paid_at"`
 
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 	CreatedBy string    `json:"created_by"`
@@ -71,17 +88,322 @@ func (PaymentGatewayTransactionT) TableName() string {
 	return TableName.PGTransactions // use your constants package
 }
 
+type APIKeyT struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	UserCode      string     `json:"user_code" gorm:"type:varchar(50);not null;index"`
+	Name          string     `json:"name" gorm:"type:varchar(100)"`
+	Prefix        string     `json:"prefix" gorm:"type:varchar(20);uniqueIndex;not null"`
+	KeyHash       string     `json:"-" gorm:"type:varchar(128);not null"`
+	SigningSecret string     `json:"-" gorm:"type:varchar(200)"`
+	Scopes        string     `json:"scopes" gorm:"type:varchar(255)"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	LastUsedAt    *time.Time `json:"last_used_at"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	CreatedBy     string     `json:"created_by"`
+}
+
+func (APIKeyT) TableName() string {
+	return TableName.APIKeys
+}
+
+type RequestNonceT struct {
+	ID        uint64    `json:"id" gorm:"primaryKey"`
+	KeyPrefix string    `json:"key_prefix" gorm:"type:varchar(20);not null;uniqueIndex:idx_request_nonce"`
+	Nonce     string    `json:"nonce" gorm:"type:varchar(64);not null;uniqueIndex:idx_request_nonce"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime;index"`
+}
+
+func (RequestNonceT) TableName() string {
+	return TableName.RequestNonces
+}
+
+type OrganizationT struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Code      string    `json:"code" gorm:"type:varchar(50);uniqueIndex;not null"`
+	Name      string    `json:"name" gorm:"type:varchar(100)"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	CreatedBy string    `json:"created_by"`
+}
+
+func (OrganizationT) TableName() string {
+	return TableName.Organizations
+}
+
+// OrganizationMemberT links a user to the organization that owns their
+// credentials and transactions. A user belongs to one organization.
+type OrganizationMemberT struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	OrganizationCode string    `json:"organization_code" gorm:"type:varchar(50);not null;index"`
+	UserID           uint      `json:"user_id" gorm:"not null;uniqueIndex"`
+	Username         string    `json:"username" gorm:"type:varchar(30);not null"`
+	CreatedAt        time.Time `json:"created_at" gorm:"autoCreateTime"`
+	CreatedBy        string    `json:"created_by"`
+}
+
+func (OrganizationMemberT) TableName() string {
+	return TableName.OrgMembers
+}
+
+// WebhookDeliveryT is one outbound merchant event waiting for, or done with,
+// delivery. Workers claim rows whose next_attempt_at has passed.
+type WebhookDeliveryT struct {
+	ID               uint64         `json:"id" gorm:"primaryKey"`
+	OrganizationCode string         `json:"organization_code" gorm:"type:varchar(50);index"`
+	VendorCode       string         `json:"vendor_code" gorm:"type:varchar(100);index"`
+	OrderID          string         `json:"order_id" gorm:"type:varchar(64);index"`
+	EventType        string         `json:"event_type" gorm:"type:varchar(50)"`
+	CallbackURL      string         `json:"callback_url" gorm:"type:varchar(200)"`
+	Payload          datatypes.JSON `json:"payload" gorm:"type:jsonb"`
+	Status           string         `json:"status" gorm:"type:varchar(20);default:'pending';index:idx_webhook_delivery_due"`
+	Attempts         int            `json:"attempts" gorm:"default:0"`
+	MaxAttempts      int            `json:"max_attempts"`
+	NextAttemptAt    time.Time      `json:"next_attempt_at" gorm:"index:idx_webhook_delivery_due"`
+	LastStatusCode   int            `json:"last_status_code"`
+	LastError        string         `json:"last_error" gorm:"type:text"`
+	DeliveredAt      *time.Time     `json:"delivered_at"`
+	CreatedAt        time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (WebhookDeliveryT) TableName() string {
+	return TableName.WebhookDeliveries
+}
+
+type WebhookAttemptT struct {
+	ID              uint64    `json:"id" gorm:"primaryKey"`
+	DeliveryID      uint64    `json:"delivery_id" gorm:"not null;index"`
+	AttemptNo       int       `json:"attempt_no"`
+	StatusCode      int       `json:"status_code"`
+	LatencyMs       int64     `json:"latency_ms"`
+	ResponseSnippet string    `json:"response_snippet" gorm:"type:text"`
+	Error           string    `json:"error" gorm:"type:text"`
+	CreatedAt       time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (WebhookAttemptT) TableName() string {
+	return TableName.WebhookAttempts
+}
+
+// IdempotencyKeyT remembers a create request by its Idempotency-Key so a
+// retry gets the stored response instead of a second payment.
+type IdempotencyKeyT struct {
+	ID               uint64 `json:"id" gorm:"primaryKey"`
+	OrganizationCode string `json:"organization_code" gorm:"type:varchar(50);not null;uniqueIndex:idx_idempotency_key"`
+	Key              string `json:"key" gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_key"`
+	Fingerprint      string `json:"fingerprint" gorm:"type:varchar(64);not null"`
+	Status           string `json:"status" gorm:"type:varchar(20);not null"`
+	// OrderID is the order id generated for the request, reused by retries.
+	OrderID      string    `json:"order_id" gorm:"type:varchar(64)"`
+	ResponseCode int       `json:"response_code"`
+	ResponseBody string    `json:"response_body" gorm:"type:text"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime;index"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (IdempotencyKeyT) TableName() string {
+	return TableName.IdempotencyKeys
+}
+
+// ReconciliationRunT is one pass of the reconciler over open transactions.
+type ReconciliationRunT struct {
+	ID            uint64     `json:"id" gorm:"primaryKey"`
+	Trigger       string     `json:"trigger" gorm:"type:varchar(30)"`
+	Checked       int        `json:"checked"`
+	Corrected     int        `json:"corrected"`
+	Discrepancies int        `json:"discrepancies"`
+	Failed        int        `json:"failed"`
+	StartedAt     time.Time  `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+	CreatedBy     string     `json:"created_by"`
+}
+
+func (ReconciliationRunT) TableName() string {
+	return TableName.ReconciliationRuns
+}
+
+// ReconciliationItemT records a transaction where the bridge and the vendor
+// disagreed, or where the vendor could not be asked.
+type ReconciliationItemT struct {
+	ID               uint64    `json:"id" gorm:"primaryKey"`
+	RunID            uint64    `json:"run_id" gorm:"not null;index"`
+	OrganizationCode string    `json:"organization_code" gorm:"type:varchar(50);index"`
+	VendorCode       string    `json:"vendor_code" gorm:"type:varchar(100)"`
+	OrderID          string    `json:"order_id" gorm:"type:varchar(64);index"`
+	StoredStatus     string    `json:"stored_status" gorm:"type:varchar(50)"`
+	StoredAmount     int       `json:"stored_amount"`
+	VendorStatus     string    `json:"vendor_status" gorm:"type:varchar(50)"`
+	CanonicalStatus  string    `json:"canonical_status" gorm:"type:varchar(50)"`
+	VendorAmount     string    `json:"vendor_amount" gorm:"type:varchar(30)"`
+	Action           string    `json:"action" gorm:"type:varchar(20)"`
+	Detail           string    `json:"detail" gorm:"type:text"`
+	CreatedAt        time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (ReconciliationItemT) TableName() string {
+	return TableName.ReconciliationItems
+}
+
+// SettlementImportT is an uploaded vendor settlement file and the report of
+// matching it against the bridge transactions.
+type SettlementImportT struct {
+	ID                  uint64         `json:"id" gorm:"primaryKey"`
+	OrganizationCode    string         `json:"organization_code" gorm:"type:varchar(50);index"`
+	VendorCode          string         `json:"vendor_code" gorm:"type:varchar(100);index"`
+	FileName            string         `json:"file_name" gorm:"type:varchar(255)"`
+	PeriodStart         *time.Time     `json:"period_start"`
+	PeriodEnd           *time.Time     `json:"period_end"`
+	Rows                int            `json:"rows"`
+	Ignored             int            `json:"ignored"`
+	Matched             int            `json:"matched"`
+	AmountMismatched    int            `json:"amount_mismatched"`
+	MissingInBridge     int            `json:"missing_in_bridge"`
+	MissingInSettlement int            `json:"missing_in_settlement"`
+	TotalGross          float64        `json:"total_gross"`
+	TotalFee            float64        `json:"total_fee"`
+	TotalNet            float64        `json:"total_net"`
+	Items               datatypes.JSON `json:"items,omitempty" gorm:"type:jsonb"`
+	CreatedAt           time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	CreatedBy           string         `json:"created_by"`
+}
+
+func (SettlementImportT) TableName() string {
+	return TableName.SettlementImports
+}
+
+// RefundT is a full or partial refund of a transaction. Pending refunds count
+// against the refundable amount until the vendor has answered.
+type RefundT struct {
+	ID               uint64         `json:"id" gorm:"primaryKey"`
+	RefundID         string         `json:"refund_id" gorm:"type:varchar(64);uniqueIndex;not null"`
+	OrderID          string         `json:"order_id" gorm:"type:varchar(64);index;not null"`
+	OrganizationCode string         `json:"organization_code" gorm:"type:varchar(50);index"`
+	VendorCode       string         `json:"vendor_code" gorm:"type:varchar(100)"`
+	Amount           int            `json:"amount" gorm:"not null"`
+	Reason           string         `json:"reason" gorm:"type:varchar(255)"`
+	Status           string         `json:"status" gorm:"type:varchar(20);index"`
+	VendorReference  string         `json:"vendor_reference" gorm:"type:varchar(100)"`
+	VendorStatus     string         `json:"vendor_status" gorm:"type:varchar(50)"`
+	VendorPayload    datatypes.JSON `json:"vendor_payload" gorm:"type:jsonb"`
+	FailureReason    string         `json:"failure_reason,omitempty" gorm:"type:text"`
+	CreatedAt        time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	CreatedBy        string         `json:"created_by"`
+	UpdatedAt        time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	UpdatedBy        string         `json:"updated_by"`
+}
+
+func (RefundT) TableName() string {
+	return TableName.Refunds
+}
+
+// TransactionEventT is one status change of a transaction. Rows are only ever
+// inserted, in the same database transaction as the change itself.
+type TransactionEventT struct {
+	ID               uint64         `json:"id" gorm:"primaryKey"`
+	TransactionID    uint64         `json:"transaction_id" gorm:"not null;index"`
+	OrderID          string         `json:"order_id" gorm:"type:varchar(64);index;not null"`
+	OrganizationCode string         `json:"organization_code" gorm:"type:varchar(50);index"`
+	FromStatus       string         `json:"from_status" gorm:"type:varchar(50)"`
+	ToStatus         string         `json:"to_status" gorm:"type:varchar(50);not null"`
+	Source           string         `json:"source" gorm:"type:varchar(30);not null"`
+	VendorStatus     string         `json:"vendor_status" gorm:"type:varchar(50)"`
+	PayloadRef       string         `json:"payload_ref" gorm:"type:varchar(100)"`
+	Payload          datatypes.JSON `json:"payload,omitempty" gorm:"type:jsonb"`
+	CreatedAt        time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	CreatedBy        string         `json:"created_by"`
+}
+
+func (TransactionEventT) TableName() string {
+	return TableName.TransactionEvents
+}
+
+// NotificationInboxT is an inbound vendor notification exactly as received. It
+// is stored before processing so a mishandled notification can be replayed.
+type NotificationInboxT struct {
+	ID               uint64         `json:"id" gorm:"primaryKey"`
+	VendorCode       string         `json:"vendor_code" gorm:"type:varchar(100);index"`
+	OrganizationCode string         `json:"organization_code" gorm:"type:varchar(50);index"`
+	OrderID          string         `json:"order_id" gorm:"type:varchar(64);index"`
+	Path             string         `json:"path" gorm:"type:varchar(255)"`
+	Headers          datatypes.JSON `json:"headers,omitempty" gorm:"type:jsonb"`
+	Body             string         `json:"body,omitempty" gorm:"type:text"`
+	SourceIP         string         `json:"source_ip" gorm:"type:varchar(64)"`
+	Verification     string         `json:"verification" gorm:"type:varchar(30)"`
+	// SignatureVerified records that the signature checked out when the
+	// notification arrived; replays rely on it.
+	SignatureVerified bool       `json:"signature_verified" gorm:"not null;default:false"`
+	Outcome           string     `json:"outcome" gorm:"type:varchar(30);index"`
+	OutcomeDetail     string     `json:"outcome_detail" gorm:"type:text"`
+	ResponseCode      int        `json:"response_code"`
+	ReplayCount       int        `json:"replay_count" gorm:"default:0"`
+	ReceivedAt        time.Time  `json:"received_at" gorm:"autoCreateTime;index"`
+	ProcessedAt       *time.Time `json:"processed_at"`
+}
+
+func (NotificationInboxT) TableName() string {
+	return TableName.NotificationInbox
+}
+
+// ProcessedNotificationT marks a vendor notification as handled. A resend with
+// the same vendor code, vendor transaction id and canonical status is a
+// duplicate. The raw vendor status is not part of the key because a vendor can
+// send the same one for different states, like a Midtrans capture that is
+// challenged and later accepted.
+type ProcessedNotificationT struct {
+	ID            uint64    `json:"id" gorm:"primaryKey"`
+	VendorCode    string    `json:"vendor_code" gorm:"type:varchar(100);not null;uniqueIndex:idx_processed_notification_status"`
+	TransactionID string    `json:"transaction_id" gorm:"type:varchar(100);not null;uniqueIndex:idx_processed_notification_status"`
+	Status        string    `json:"status" gorm:"type:varchar(50);not null;default:'';uniqueIndex:idx_processed_notification_status"`
+	VendorStatus  string    `json:"vendor_status" gorm:"type:varchar(50);not null"`
+	OrderID       string    `json:"order_id" gorm:"type:varchar(64)"`
+	InboxID       uint64    `json:"inbox_id"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (ProcessedNotificationT) TableName() string {
+	return TableName.ProcessedNotifications
+}
+
 // Variable
 
 // list of table name
 type TableNameStruct struct {
-	User           string
-	PGCredentials  string
-	PGTransactions string
+	User                   string
+	PGCredentials          string
+	PGTransactions         string
+	APIKeys                string
+	RequestNonces          string
+	Organizations          string
+	OrgMembers             string
+	WebhookDeliveries      string
+	WebhookAttempts        string
+	IdempotencyKeys        string
+	ReconciliationRuns     string
+	ReconciliationItems    string
+	SettlementImports      string
+	Refunds                string
+	TransactionEvents      string
+	NotificationInbox      string
+	ProcessedNotifications string
 }
 
 var TableName = TableNameStruct{
-	User:           "user",
-	PGCredentials:  "payment_gateway_credentials",
-	PGTransactions: "payment_gateway_transaction",
+	User:                   "user",
+	PGCredentials:          "payment_gateway_credentials",
+	PGTransactions:         "payment_gateway_transaction",
+	APIKeys:                "api_keys",
+	RequestNonces:          "request_nonces",
+	Organizations:          "organizations",
+	OrgMembers:             "organization_members",
+	WebhookDeliveries:      "webhook_deliveries",
+	WebhookAttempts:        "webhook_attempts",
+	IdempotencyKeys:        "idempotency_keys",
+	ReconciliationRuns:     "reconciliation_runs",
+	ReconciliationItems:    "reconciliation_items",
+	SettlementImports:      "settlement_imports",
+	Refunds:                "refunds",
+	TransactionEvents:      "transaction_events",
+	NotificationInbox:      "notification_inbox",
+	ProcessedNotifications: "processed_notifications",
 }
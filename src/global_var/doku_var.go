@@ -0,0 +1,76 @@
+package global_var
+
+// struct
+
+type DOKU_LineItem struct {
+	Name     string `json:"name"`
+	Price    int    `json:"price"`
+	Quantity int    `json:"quantity"`
+}
+
+type DOKU_Order struct {
+	Amount        int             `json:"amount"`
+	InvoiceNumber string          `json:"invoice_number"`
+	Currency      string          `json:"currency,omitempty"`
+	CallbackURL   string          `json:"callback_url,omitempty"`
+	LineItems     []DOKU_LineItem `json:"line_items,omitempty"`
+}
+
+type DOKU_Payment struct {
+	PaymentDueDate     int      `json:"payment_due_date,omitempty"`
+	PaymentMethodTypes []string `json:"payment_method_types,omitempty"`
+}
+
+type DOKU_Customer struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+}
+
+type DOKU_RequestBody struct {
+	Order    DOKU_Order     `json:"order"`
+	Payment  *DOKU_Payment  `json:"payment,omitempty"`
+	Customer *DOKU_Customer `json:"customer,omitempty"`
+}
+
+type DOKU_PaymentResult struct {
+	URL         string `json:"url"`
+	TokenID     string `json:"token_id"`
+	ExpiredDate string `json:"expired_date"`
+}
+
+type DOKU_ResultBody struct {
+	Message  []string `json:"message"`
+	Response struct {
+		Order   DOKU_Order         `json:"order"`
+		Payment DOKU_PaymentResult `json:"payment"`
+		UUID    int64              `json:"uuid"`
+	} `json:"response"`
+}
+
+type DOKU_ID struct {
+	ID string `json:"id"`
+}
+
+type DOKU_Transaction struct {
+	Status            string `json:"status"`
+	Date              string `json:"date"`
+	OriginalRequestID string `json:"original_request_id"`
+}
+
+// DOKU_StatusBody is both the status inquiry response and the notification payload.
+type DOKU_StatusBody struct {
+	Order       DOKU_Order       `json:"order"`
+	Transaction DOKU_Transaction `json:"transaction"`
+	Service     DOKU_ID          `json:"service"`
+	Acquirer    DOKU_ID          `json:"acquirer"`
+	Channel     DOKU_ID          `json:"channel"`
+}
+
+type DOKU_ErrorBody struct {
+	Message []string `json:"message"`
+	Error   struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
@@ -35,6 +35,8 @@ type PGEnvUrl struct {
 	Midtrans     PGEnvStatus
 	MidtransSend PGEnvStatus
 	Xendit       PGEnvStatus
+	HitPay       PGEnvStatus
+	Doku         PGEnvStatus
 }
 
 // Global Variable
@@ -63,6 +65,99 @@ var (
 	TxStatusFailed         = "failed"
 	TxStatusError          = "error"
 	TxStatusRefunded       = "refunded"
+	TxStatusCancelled      = "cancelled"
+
+	TxStatusPartiallyRefunded = "partially_refunded"
+)
+
+// OpenTxStatuses are the statuses of transactions still waiting on the customer.
+var OpenTxStatuses = []string{TxStatusPending, TxStatusWaitingPayment}
+
+// Sources of a transaction status change in the event log
+var (
+	TxEventSourceNotification     = "notification"
+	TxEventSourceRedirectCallback = "redirect_callback"
+	TxEventSourceReconciler       = "reconciler"
+	TxEventSourceExpirySweeper    = "expiry_sweeper"
+	TxEventSourceManual           = "manual"
+	TxEventSourceAPI              = "api"
+)
+
+var (
+	NotificationVerificationPending  = "pending"
+	NotificationVerificationVerified = "verified"
+	NotificationVerificationInvalid  = "invalid_signature"
+	NotificationVerificationFailed   = "unverified"
+)
+
+var (
+	NotificationOutcomeReceived  = "received"
+	NotificationOutcomeApplied   = "applied"
+	NotificationOutcomeIgnored   = "ignored"
+	NotificationOutcomeDuplicate = "duplicate"
+	NotificationOutcomeRejected  = "rejected"
+	NotificationOutcomeError     = "error"
+)
+
+var (
+	RefundStatusPending   = "pending"
+	RefundStatusSucceeded = "succeeded"
+	RefundStatusFailed    = "failed"
+)
+
+var (
+	IdempotencyStatusInProgress = "in_progress"
+	IdempotencyStatusCompleted  = "completed"
+	IdempotencyStatusFailed     = "failed"
+)
+
+var (
+	ReconcileActionCorrected = "corrected"
+	ReconcileActionReported  = "reported"
+	ReconcileActionError     = "error"
+)
+
+var (
+	WebhookStatusPending   = "pending"
+	WebhookStatusDelivered = "delivered"
+	WebhookStatusDead      = "dead"
+)
+
+var (
+	ScopePaymentsCreate         = "payments:create"
+	ScopePaymentsRead           = "payments:read"
+	ScopeCredentialsRead        = "credentials:read"
+	ScopeCredentialsReadSecrets = "credentials:read_secrets"
+	ScopeCredentialsManage      = "credentials:manage"
+	ScopeAPIKeysManage          = "api_keys:manage"
+	ScopeMembersManage          = "members:manage"
+	ScopeWebhooksManage         = "webhooks:manage"
+	ScopeRefundsCreate          = "refunds:create"
+)
+
+var APIKeyScopes = []string{ScopePaymentsCreate, ScopePaymentsRead, ScopeCredentialsRead, ScopeCredentialsReadSecrets, ScopeCredentialsManage, ScopeAPIKeysManage, ScopeMembersManage, ScopeWebhooksManage, ScopeRefundsCreate}
+
+var (
+	RoleAdmin             = "admin"
+	RoleMerchantOwner     = "merchant_owner"
+	RoleMerchantDeveloper = "merchant_developer"
+	RoleFinance           = "finance"
+)
+
+// RolePermissions lists the scopes each user role holds. API keys never get
+// more than the role of the user that owns them. credentials:read lists
+// credentials without their vendor keys; credentials:read_secrets or
+// credentials:manage is needed to see them.
+var RolePermissions = map[string][]string{
+	RoleAdmin:             APIKeyScopes,
+	RoleMerchantOwner:     APIKeyScopes,
+	RoleMerchantDeveloper: {ScopePaymentsCreate, ScopePaymentsRead, ScopeCredentialsRead, ScopeAPIKeysManage, ScopeWebhooksManage},
+	RoleFinance:           {ScopePaymentsRead},
+}
+
+var (
+	CheckoutModeQR      = "qr"
+	CheckoutModeInvoice = "invoice"
 )
 
 var PGUrlList = PGEnvUrl{
@@ -78,4 +173,12 @@ var PGUrlList = PGEnvUrl{
 		Dev:  "https://api.sandbox.xendit.co",
 		Prod: "https://api.xendit.co",
 	},
+	HitPay: PGEnvStatus{
+		Dev:  "https://api.sandbox.hit-pay.com",
+		Prod: "https://api.hit-pay.com",
+	},
+	Doku: PGEnvStatus{
+		Dev:  "https://api-sandbox.doku.com",
+		Prod: "https://api.doku.com",
+	},
 }
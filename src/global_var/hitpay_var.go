@@ -0,0 +1,49 @@
+package global_var
+
+// struct
+
+type HTPY_RequestBody struct {
+	Amount          string   `json:"amount"`
+	Currency        string   `json:"currency"`
+	Email           string   `json:"email,omitempty"`
+	Name            string   `json:"name,omitempty"`
+	Phone           string   `json:"phone,omitempty"`
+	Purpose         string   `json:"purpose,omitempty"`
+	ReferenceNumber string   `json:"reference_number"`
+	RedirectURL     string   `json:"redirect_url,omitempty"`
+	Webhook         string   `json:"webhook,omitempty"`
+	ExpiryDate      string   `json:"expiry_date,omitempty"`
+	PaymentMethods  []string `json:"payment_methods,omitempty"`
+}
+
+type HTPY_Payment struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"`
+	Amount      string `json:"amount"`
+	Currency    string `json:"currency"`
+	PaymentType string `json:"payment_type"`
+}
+
+type HTPY_ResultBody struct {
+	ID              string         `json:"id"`
+	Name            string         `json:"name"`
+	Email           string         `json:"email"`
+	Phone           string         `json:"phone"`
+	Amount          string         `json:"amount"`
+	Currency        string         `json:"currency"`
+	Status          string         `json:"status"`
+	Purpose         string         `json:"purpose"`
+	ReferenceNumber string         `json:"reference_number"`
+	PaymentMethods  []string       `json:"payment_methods"`
+	URL             string         `json:"url"`
+	RedirectURL     string         `json:"redirect_url"`
+	Webhook         string         `json:"webhook"`
+	ExpiryDate      string         `json:"expiry_date"`
+	CreatedAt       string         `json:"created_at"`
+	UpdatedAt       string         `json:"updated_at"`
+	Payments        []HTPY_Payment `json:"payments"`
+}
+
+type HTPY_ErrorBody struct {
+	Message string `json:"message"`
+}
@@ -17,9 +17,10 @@ type XDNT_RequestBody struct {
 	ReferenceID string                        `json:"reference_id"`
 	Type        string                        `json:"type"`
 	Currency    string                        `json:"currency"`
+	ChannelCode string                        `json:"channel_code,omitempty"`
 	Amount      float64                       `json:"amount"`
-	ExpiresAt   time.Time                     `json:"expires_at"`
-	Basket      []XDNT_ItemDetail_RequestBody `json:"basket"`
+	ExpiresAt   *time.Time                    `json:"expires_at,omitempty"`
+	Basket      []XDNT_ItemDetail_RequestBody `json:"basket,omitempty"`
 }
 
 type XDNT_ItemDetail_ResultBody struct {
@@ -46,3 +47,86 @@ type XDNT_ResultBody struct {
 	QRString    string                       `json:"qr_string"`
 	Status      string                       `json:"status"`
 }
+
+type XDNT_ErrorBody struct {
+	ErrorCode string `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+type XDNT_QRPaymentDetail struct {
+	ReceiptID string `json:"receipt_id"`
+	Source    string `json:"source"`
+}
+
+type XDNT_QRPaymentBody struct {
+	ID            string               `json:"id"`
+	BusinessID    string               `json:"business_id"`
+	Currency      string               `json:"currency"`
+	Amount        float64              `json:"amount"`
+	Status        string               `json:"status"`
+	Created       time.Time            `json:"created"`
+	QRID          string               `json:"qr_id"`
+	QRString      string               `json:"qr_string"`
+	ReferenceID   string               `json:"reference_id"`
+	Type          string               `json:"type"`
+	ChannelCode   string               `json:"channel_code"`
+	ExpiresAt     time.Time            `json:"expires_at"`
+	PaymentDetail XDNT_QRPaymentDetail `json:"payment_detail"`
+}
+
+type XDNT_QRPaymentListBody struct {
+	Data    []XDNT_QRPaymentBody `json:"data"`
+	HasMore bool                 `json:"has_more"`
+}
+
+type XDNT_QRCallbackBody struct {
+	Event      string             `json:"event"`
+	APIVersion string             `json:"api_version"`
+	BusinessID string             `json:"business_id"`
+	Created    time.Time          `json:"created"`
+	Data       XDNT_QRPaymentBody `json:"data"`
+}
+
+type XDNT_InvoiceCustomer struct {
+	GivenNames   string `json:"given_names,omitempty"`
+	Surname      string `json:"surname,omitempty"`
+	Email        string `json:"email,omitempty"`
+	MobileNumber string `json:"mobile_number,omitempty"`
+}
+
+type XDNT_InvoiceItem struct {
+	Name     string  `json:"name"`
+	Quantity int     `json:"quantity"`
+	Price    float64 `json:"price"`
+	Category string  `json:"category,omitempty"`
+}
+
+type XDNT_InvoiceRequestBody struct {
+	ExternalID         string                `json:"external_id"`
+	Amount             float64               `json:"amount"`
+	Description        string                `json:"description,omitempty"`
+	InvoiceDuration    int                   `json:"invoice_duration,omitempty"`
+	Customer           *XDNT_InvoiceCustomer `json:"customer,omitempty"`
+	Items              []XDNT_InvoiceItem    `json:"items,omitempty"`
+	SuccessRedirectURL string                `json:"success_redirect_url,omitempty"`
+	FailureRedirectURL string                `json:"failure_redirect_url,omitempty"`
+	Currency           string                `json:"currency"`
+	PaymentMethods     []string              `json:"payment_methods,omitempty"`
+}
+
+// XDNT_InvoiceBody is both the invoice API response and the invoice callback payload.
+type XDNT_InvoiceBody struct {
+	ID             string  `json:"id"`
+	ExternalID     string  `json:"external_id"`
+	UserID         string  `json:"user_id"`
+	Status         string  `json:"status"`
+	MerchantName   string  `json:"merchant_name"`
+	Amount         float64 `json:"amount"`
+	PaidAmount     float64 `json:"paid_amount"`
+	PaymentMethod  string  `json:"payment_method"`
+	PaymentChannel string  `json:"payment_channel"`
+	InvoiceURL     string  `json:"invoice_url"`
+	ExpiryDate     string  `json:"expiry_date"`
+	PaidAt         string  `json:"paid_at"`
+	Currency       string  `json:"currency"`
+}
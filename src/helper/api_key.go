@@ -0,0 +1,66 @@
+package helper
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+const APIKeyPrefix = "pgb"
+
+// GenerateAPIKey returns a new API key in the form pgb_<lookup prefix>_<secret>
+// together with its lookup prefix and the hash to store.
+func GenerateAPIKey() (key, lookup, hash string, err error) {
+	lookupBytes := make([]byte, 6)
+	if _, err = rand.Read(lookupBytes); err != nil {
+		return "", "", "", err
+	}
+	secretBytes := make([]byte, 32)
+	if _, err = rand.Read(secretBytes); err != nil {
+		return "", "", "", err
+	}
+
+	lookup = hex.EncodeToString(lookupBytes)
+	key = APIKeyPrefix + "_" + lookup + "_" + hex.EncodeToString(secretBytes)
+	return key, lookup, HashAPIKey(key), nil
+}
+
+// HashAPIKey hashes an API key for storage. Keys are random 256 bit values so a
+// single SHA-256 is enough and keeps per request verification cheap.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseAPIKey returns the lookup prefix of a well formed API key
+func ParseAPIKey(key string) (string, error) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != APIKeyPrefix || len(parts[1]) != 12 || len(parts[2]) != 64 {
+		return "", errors.New("malformed api key")
+	}
+	return parts[1], nil
+}
+
+// GenerateSigningSecret returns a random secret for HMAC signed requests
+func GenerateSigningSecret() (string, error) {
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secretBytes), nil
+}
+
+// SignRequest computes the X-PGB-Signature value for a server to server call:
+// hex(HMAC-SHA256(secret, METHOD\nPATH\nTIMESTAMP\nNONCE\nhex(SHA256(body)))).
+// Callers sign the same string to produce the header.
+func SignRequest(secret, method, path, timestamp, nonce string, body []byte) string {
+	digest := sha256.Sum256(body)
+	payload := strings.ToUpper(method) + "\n" + path + "\n" + timestamp + "\n" + nonce + "\n" + hex.EncodeToString(digest[:])
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
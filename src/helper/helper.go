@@ -41,6 +41,32 @@ func GetUsernameFiber(c *fiber.Ctx) string {
 	return ""
 }
 
+// GetOrganizationFiber returns the organization code of the authenticated caller
+func GetOrganizationFiber(c *fiber.Ctx) string {
+	organization, _ := c.Locals("organization").(string)
+	return organization
+}
+
+// GetScopesFiber returns the permissions granted to the authenticated caller
+func GetScopesFiber(c *fiber.Ctx) []string {
+	scopes, _ := c.Locals("scopes").([]string)
+	return scopes
+}
+
+// GetIdempotencyKeyIDFiber returns the id of the Idempotency-Key record the
+// request runs under, or 0 without one.
+func GetIdempotencyKeyIDFiber(c *fiber.Ctx) uint64 {
+	id, _ := c.Locals("idempotency_key_id").(uint64)
+	return id
+}
+
+// GetIdempotentOrderIDFiber returns the order id an earlier, failed attempt
+// with the same Idempotency-Key generated.
+func GetIdempotentOrderIDFiber(c *fiber.Ctx) string {
+	orderID, _ := c.Locals("idempotency_order_id").(string)
+	return orderID
+}
+
 // GetAuth extracts basic auth credentials from Fiber context
 func GetAuth(c *fiber.Ctx) (string, bool) {
 	auth := c.Get("Authorization")
@@ -57,9 +83,13 @@ func GetAuth(c *fiber.Ctx) (string, bool) {
 	return "", false
 }
 
-func GenerateOrderID(PGID string) string {
-	timestamp := time.Now().Unix()
-	return fmt.Sprintf("%s-%d", PGID, timestamp)
+// GetRequestHeaders copies the inbound request headers into an http.Header
+func GetRequestHeaders(c *fiber.Ctx) http.Header {
+	headers := http.Header{}
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		headers.Add(string(key), string(value))
+	})
+	return headers
 }
 
 func GenerateQRCodeBase64(url string) (string, error) {
@@ -0,0 +1,110 @@
+package helper
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultOrderIDTemplate is used by credentials without their own template.
+const DefaultOrderIDTemplate = "{vendor}-{ulid}"
+
+// Order ID template placeholders. Every template needs {ulid} or {seq} so the
+// result is unique across replicas.
+const (
+	OrderIDPlaceholderPrefix = "{prefix}"
+	OrderIDPlaceholderVendor = "{vendor}"
+	OrderIDPlaceholderULID   = "{ulid}"
+	OrderIDPlaceholderSeq    = "{seq}"
+)
+
+var orderIDDatePlaceholders = map[string]string{
+	"{yyyyMMdd}": "20060102",
+	"{yyyy}":     "2006",
+	"{yy}":       "06",
+	"{MM}":       "01",
+	"{dd}":       "02",
+}
+
+var orderIDPlaceholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// ValidateOrderIDTemplate checks that a template only uses known placeholders
+// and contains a unique part.
+func ValidateOrderIDTemplate(template string) error {
+	for _, placeholder := range orderIDPlaceholderPattern.FindAllString(template, -1) {
+		switch placeholder {
+		case OrderIDPlaceholderPrefix, OrderIDPlaceholderVendor, OrderIDPlaceholderULID, OrderIDPlaceholderSeq:
+			continue
+		}
+		if _, ok := orderIDDatePlaceholders[placeholder]; !ok {
+			return fmt.Errorf("unknown placeholder %s in order id template", placeholder)
+		}
+	}
+	if !strings.Contains(template, OrderIDPlaceholderULID) && !strings.Contains(template, OrderIDPlaceholderSeq) {
+		return fmt.Errorf("order id template must contain %s or %s", OrderIDPlaceholderULID, OrderIDPlaceholderSeq)
+	}
+	return nil
+}
+
+// GenerateOrderID renders an order id template. nextSeq is only called when
+// the template uses {seq}.
+func GenerateOrderID(template, prefix, vendorCode string, now time.Time, nextSeq func() (int64, error)) (string, error) {
+	if template == "" {
+		template = DefaultOrderIDTemplate
+	}
+	if err := ValidateOrderIDTemplate(template); err != nil {
+		return "", err
+	}
+
+	replacements := []string{
+		OrderIDPlaceholderPrefix, prefix,
+		OrderIDPlaceholderVendor, vendorCode,
+	}
+	for placeholder, layout := range orderIDDatePlaceholders {
+		replacements = append(replacements, placeholder, now.Format(layout))
+	}
+
+	if strings.Contains(template, OrderIDPlaceholderULID) {
+		id, err := NewULID(now)
+		if err != nil {
+			return "", err
+		}
+		replacements = append(replacements, OrderIDPlaceholderULID, id)
+	}
+	if strings.Contains(template, OrderIDPlaceholderSeq) {
+		seq, err := nextSeq()
+		if err != nil {
+			return "", err
+		}
+		replacements = append(replacements, OrderIDPlaceholderSeq, strconv.FormatInt(seq, 10))
+	}
+
+	return strings.NewReplacer(replacements...).Replace(template), nil
+}
+
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID returns a 26 character ULID: 48 bits of millisecond time followed
+// by 80 random bits, Crockford base32 encoded. ULIDs sort by creation time.
+func NewULID(now time.Time) (string, error) {
+	var id [16]byte
+	binary.BigEndian.PutUint64(id[:8], uint64(now.UnixMilli())<<16)
+	if _, err := rand.Read(id[6:]); err != nil {
+		return "", err
+	}
+
+	// 128 bits are written as 26 base32 digits, the first one carrying 3 bits
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordBase32[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out), nil
+}
@@ -0,0 +1,123 @@
+package helper
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var ulidPattern = regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`)
+
+func TestNewULID(t *testing.T) {
+	now := time.Date(2026, 3, 14, 9, 26, 53, 0, time.UTC)
+
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		id, err := NewULID(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ulidPattern.MatchString(id) {
+			t.Fatalf("NewULID() = %q, want 26 Crockford base32 characters", id)
+		}
+		if seen[id] {
+			t.Fatalf("NewULID() returned %q twice", id)
+		}
+		seen[id] = true
+	}
+
+	earlier, err := NewULID(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	later, err := NewULID(now.Add(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if earlier >= later {
+		t.Errorf("NewULID() = %q after %q, want it to sort later", later, earlier)
+	}
+}
+
+func TestGenerateOrderID(t *testing.T) {
+	now := time.Date(2026, 3, 14, 9, 26, 53, 0, time.UTC)
+	seq := func() (int64, error) { return 42, nil }
+
+	tests := []struct {
+		name     string
+		template string
+		want     string // regexp
+	}{
+		{"default template", "", `^MIDTRANS-01-[0-9A-Z]{26}$`},
+		{"prefix and seq", "{prefix}-{seq}", `^SHOP-42$`},
+		{"date placeholders", "{yyyyMMdd}/{yy}{MM}{dd}/{yyyy}-{seq}", `^20260314/260314/2026-42$`},
+		{"literal text", "INV{ulid}", `^INV[0-9A-Z]{26}$`},
+		{"ulid and seq", "{vendor}-{seq}-{ulid}", `^MIDTRANS-01-42-[0-9A-Z]{26}$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateOrderID(tt.template, "SHOP", "MIDTRANS-01", now, seq)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !regexp.MustCompile(tt.want).MatchString(got) {
+				t.Errorf("GenerateOrderID(%q) = %q, want match for %s", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateOrderIDUnique(t *testing.T) {
+	now := time.Now()
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		id, err := GenerateOrderID("", "", "XENDIT-01", now, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[id] {
+			t.Fatalf("GenerateOrderID() returned %q twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestGenerateOrderIDSeqOnlyWhenUsed(t *testing.T) {
+	calls := 0
+	seq := func() (int64, error) { calls++; return int64(calls), nil }
+
+	if _, err := GenerateOrderID("{prefix}{ulid}", "A", "V", time.Now(), seq); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 {
+		t.Errorf("nextSeq called %d times for a template without {seq}", calls)
+	}
+
+	wantErr := errors.New("sequence unavailable")
+	_, err := GenerateOrderID("{seq}", "", "V", time.Now(), func() (int64, error) { return 0, wantErr })
+	if !errors.Is(err, wantErr) {
+		t.Errorf("GenerateOrderID() error = %v, want %v", err, wantErr)
+	}
+}
+
+func TestGenerateOrderIDRejectsTemplates(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  string
+	}{
+		{"no unique part", "{prefix}-{yyyyMMdd}", "must contain"},
+		{"unknown placeholder", "{vendor}-{ulid}-{hh}", "unknown placeholder {hh}"},
+		{"misspelled placeholder", "{ULID}", "unknown placeholder {ULID}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := GenerateOrderID(tt.template, "", "V", time.Now(), nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("GenerateOrderID(%q) error = %v, want it to contain %q", tt.template, err, tt.wantErr)
+			}
+		})
+	}
+}
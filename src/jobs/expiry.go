@@ -0,0 +1,150 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/logger"
+	"pg_bridge_go/models"
+	"pg_bridge_go/provider"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const expirySweeperName = "expiry-sweeper"
+
+var (
+	// ExpiryBatchSize is how many overdue transactions one sweep looks at.
+	ExpiryBatchSize = 100
+	// ExpiryGrace gives vendor notifications a moment to arrive before the
+	// bridge asks the vendor itself.
+	ExpiryGrace = time.Minute
+	// ExpiryForceAfter expires a transaction without vendor confirmation when
+	// the vendor status API keeps failing this long after the deadline, e.g.
+	// because the customer never opened the payment page.
+	ExpiryForceAfter = 24 * time.Hour
+)
+
+// StartExpirySweeper runs SweepExpired every interval in the background.
+func StartExpirySweeper(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			expired, err := SweepExpired(time.Now())
+			if err != nil {
+				logger.Error("Expiry sweep failed", zap.Error(err))
+				continue
+			}
+			if expired > 0 {
+				logger.Info("Expiry sweep finished", zap.Int("expired", expired))
+			}
+		}
+	}()
+	logger.Info("Expiry sweeper started", zap.Duration("interval", interval))
+}
+
+// SweepExpired confirms overdue pending transactions with the vendor, applies
+// the vendor's final status and expires the ones still open. It returns how
+// many were expired.
+func SweepExpired(now time.Time) (int, error) {
+	db := quietDB()
+
+	var overdue []db_var.PaymentGatewayTransactionT
+	err := db.
+		Where("status IN ? AND expires_at < ?", global_var.OpenTxStatuses, now.Add(-ExpiryGrace)).
+		Order("expires_at").
+		Limit(ExpiryBatchSize).
+		Find(&overdue).Error
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, transaction := range overdue {
+		ok, err := expireTransaction(transaction.ID, now)
+		if err != nil {
+			logger.Error("Failed to expire transaction",
+				zap.String("order_id", transaction.OrderID),
+				zap.String("vendor_code", transaction.Vendor),
+				zap.Error(err),
+			)
+			continue
+		}
+		if ok {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// expireTransaction asks the vendor for the status of one overdue transaction
+// and applies it. A final vendor status is kept; the transaction is only
+// expired when the vendor still reports it open. It reports whether the
+// transaction was expired.
+func expireTransaction(id uint64, now time.Time) (bool, error) {
+	expired := false
+	err := quietDB().Transaction(func(tx *gorm.DB) error {
+		transaction, err := lockTransaction(id, global_var.OpenTxStatuses, tx)
+		if err != nil || transaction == nil {
+			return err
+		}
+
+		PG, credential, err := transactionProvider(*transaction, tx)
+		if err != nil {
+			return err
+		}
+
+		Status, err := PG.GetStatus(*transaction, credential)
+		if errors.Is(err, provider.ErrTransactionNotFound) {
+			// The customer never opened the payment page
+			Status, err = &provider.TransactionStatus{OrderID: transaction.OrderID}, nil
+		}
+		if err != nil {
+			if transaction.ExpiresAt == nil || now.Sub(*transaction.ExpiresAt) < ExpiryForceAfter {
+				return fmt.Errorf("vendor status lookup: %w", err)
+			}
+			logger.Warn("Expiring transaction without vendor confirmation",
+				zap.String("order_id", transaction.OrderID),
+				zap.Error(err),
+			)
+			Status = &provider.TransactionStatus{OrderID: transaction.OrderID}
+		}
+
+		switch Status.Status {
+		case global_var.TxStatusPaid:
+			// The customer paid just before the deadline and the notification
+			// got lost. Never act on a vendor answer for a different amount
+			if !amountMatches(Status.GrossAmount, transaction.Amount) {
+				logger.Warn("Vendor amount does not match stored amount, leaving transaction open",
+					zap.String("order_id", transaction.OrderID),
+					zap.String("vendor_amount", Status.GrossAmount),
+					zap.Int("stored_amount", transaction.Amount),
+				)
+				return nil
+			}
+		case global_var.TxStatusExpired, global_var.TxStatusFailed, global_var.TxStatusCancelled:
+			// The vendor already closed the transaction, keep its final status
+		case global_var.TxStatusRefunded, global_var.TxStatusPartiallyRefunded:
+			logger.Warn("Vendor reports a refund for a transaction the bridge never saw paid",
+				zap.String("order_id", transaction.OrderID),
+				zap.String("vendor_status", Status.VendorStatus),
+			)
+			return nil
+		default:
+			// Still open at the vendor, or no vendor answer after ExpiryForceAfter
+			Status.Status = global_var.TxStatusExpired
+		}
+
+		changed, err := applyVendorStatus(*transaction, credential, Status, expirySweeperName, models.StatusCause{Source: global_var.TxEventSourceExpirySweeper}, now, tx)
+		expired = changed && Status.Status == global_var.TxStatusExpired
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return expired, nil
+}
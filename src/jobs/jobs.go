@@ -0,0 +1,74 @@
+// Package jobs holds the background jobs that keep stored transactions in
+// line with the vendors when notifications are late or lost.
+package jobs
+
+import (
+	"fmt"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/models"
+	"pg_bridge_go/provider"
+	"pg_bridge_go/webhook"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// quietDB keeps periodic jobs out of the SQL debug log.
+func quietDB() *gorm.DB {
+	return global_var.DB.Session(&gorm.Session{Logger: global_var.DB.Logger.LogMode(gormlogger.Warn)})
+}
+
+// lockTransaction locks a transaction that still has one of statuses so
+// replicas running the same job do not process it twice. It returns nil when
+// the transaction is locked elsewhere or its status has moved on.
+func lockTransaction(id uint64, statuses []string, tx *gorm.DB) (*db_var.PaymentGatewayTransactionT, error) {
+	var locked []db_var.PaymentGatewayTransactionT
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("id = ? AND status IN ?", id, statuses).
+		Find(&locked).Error
+	if err != nil || len(locked) == 0 {
+		return nil, err
+	}
+	return &locked[0], nil
+}
+
+// transactionProvider loads the credential and provider a transaction was made with.
+func transactionProvider(transaction db_var.PaymentGatewayTransactionT, tx *gorm.DB) (provider.PaymentProvider, db_var.PaymentGatewayCredentialT, error) {
+	var credential db_var.PaymentGatewayCredentialT
+	if err := tx.Where("code = ?", transaction.Vendor).First(&credential).Error; err != nil {
+		return nil, credential, fmt.Errorf("load credential: %w", err)
+	}
+
+	PG, ok := provider.ForVendorCode(transaction.Vendor)
+	if !ok {
+		return nil, credential, fmt.Errorf("no provider for vendor code %s", transaction.Vendor)
+	}
+	return PG, credential, nil
+}
+
+// applyVendorStatus writes a vendor reported status onto an open transaction
+// and queues the merchant webhook. Statuses that leave the transaction open
+// are not written. The vendor status and answer are added to cause for the
+// event log. It reports whether the stored status changed.
+func applyVendorStatus(transaction db_var.PaymentGatewayTransactionT, credential db_var.PaymentGatewayCredentialT, Status *provider.TransactionStatus, updatedBy string, cause models.StatusCause, now time.Time, tx *gorm.DB) (bool, error) {
+	if slices.Contains(global_var.OpenTxStatuses, Status.Status) {
+		return false, nil
+	}
+
+	cause.VendorStatus = Status.VendorStatus
+	cause.Payload = Status.Raw
+	previous, err := models.UpdatePGTransactionStatus(transaction.OrderID, Status.Status, Status.PaymentType, updatedBy, cause, tx)
+	if err != nil || previous == Status.Status {
+		return false, err
+	}
+
+	transaction.Status = previous
+	if err := webhook.Enqueue(credential, webhook.NewStatusChangeEvent(transaction, Status, now), tx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,320 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/logger"
+	"pg_bridge_go/models"
+	"pg_bridge_go/provider"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const reconcilerName = "reconciler"
+
+var (
+	// ReconcileBatchSize is how many transactions one run checks.
+	ReconcileBatchSize = 500
+	// ReconcileMinAge leaves fresh transactions to the vendor notifications
+	// and skips transactions checked this recently.
+	ReconcileMinAge = 5 * time.Minute
+	// ReconcileLookback is how old a transaction may be and still be checked.
+	ReconcileLookback = 7 * 24 * time.Hour
+	// ReconcileConcurrency is how many status calls run at once per credential.
+	ReconcileConcurrency = 2
+	// ReconcileRate is how many status calls per second are made per credential.
+	ReconcileRate = 5.0
+)
+
+// reconcileTxStatuses are the stored statuses a run checks: the open ones, and
+// sent and error, where the bridge lost track of a request the vendor may
+// still have taken.
+var reconcileTxStatuses = []string{
+	global_var.TxStatusPending,
+	global_var.TxStatusSent,
+	global_var.TxStatusWaitingPayment,
+	global_var.TxStatusError,
+}
+
+var ErrReconcileRunning = errors.New("a reconciliation run is already in progress")
+
+// reconcileLockKey is the Postgres advisory lock a run holds, so only one run
+// is active across all replicas and the per credential limits hold overall.
+const reconcileLockKey = 0x7062726563 // "pbrec"
+
+// StartReconciler runs a reconciliation every interval in the background.
+func StartReconciler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			run, err := StartReconcile("scheduled", reconcilerName)
+			if err != nil && !errors.Is(err, ErrReconcileRunning) {
+				logger.Error("Failed to start reconciliation", zap.Error(err))
+				continue
+			}
+			if run != nil {
+				logger.Info("Reconciliation started", zap.Uint64("run_id", run.ID))
+			}
+		}
+	}()
+	logger.Info("Reconciler started", zap.Duration("interval", interval))
+}
+
+// StartReconcile records a new run and walks open transactions in the
+// background. Only one run across all replicas is active at a time.
+func StartReconcile(trigger, createdBy string) (*db_var.ReconciliationRunT, error) {
+	lock, err := lockReconcile()
+	if err != nil {
+		return nil, err
+	}
+
+	run := db_var.ReconciliationRunT{
+		Trigger:   trigger,
+		StartedAt: time.Now(),
+		CreatedBy: createdBy,
+	}
+	if err := quietDB().Create(&run).Error; err != nil {
+		unlockReconcile(lock)
+		return nil, err
+	}
+
+	go func() {
+		defer unlockReconcile(lock)
+		if err := reconcile(&run); err != nil {
+			logger.Error("Reconciliation failed", zap.Uint64("run_id", run.ID), zap.Error(err))
+		}
+	}()
+	return &run, nil
+}
+
+// lockReconcile takes the reconciliation advisory lock on a connection of its
+// own, which the run keeps until it finishes. A replica that dies releases the
+// lock with its connection.
+func lockReconcile() (*sql.Conn, error) {
+	sqlDB, err := global_var.DB.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	var locked bool
+	if err := conn.QueryRowContext(context.Background(), "SELECT pg_try_advisory_lock($1)", reconcileLockKey).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !locked {
+		conn.Close()
+		return nil, ErrReconcileRunning
+	}
+	return conn, nil
+}
+
+func unlockReconcile(conn *sql.Conn) {
+	if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", reconcileLockKey); err != nil {
+		logger.Error("Failed to release reconciliation lock", zap.Error(err))
+	}
+	conn.Close()
+}
+
+func reconcile(run *db_var.ReconciliationRunT) error {
+	now := time.Now()
+
+	type candidate struct {
+		ID     uint64
+		Vendor string
+	}
+	var candidates []candidate
+	err := quietDB().Model(&db_var.PaymentGatewayTransactionT{}).
+		Select("id, vendor").
+		Where("status IN ?", reconcileTxStatuses).
+		Where("created_at BETWEEN ? AND ?", now.Add(-ReconcileLookback), now.Add(-ReconcileMinAge)).
+		Where("reconciled_at IS NULL OR reconciled_at < ?", now.Add(-ReconcileMinAge)).
+		Order("reconciled_at NULLS FIRST, created_at").
+		Limit(ReconcileBatchSize).
+		Scan(&candidates).Error
+	if err != nil {
+		return err
+	}
+
+	byCredential := map[string][]uint64{}
+	for _, c := range candidates {
+		byCredential[c.Vendor] = append(byCredential[c.Vendor], c.ID)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, ids := range byCredential {
+		wg.Add(1)
+		go func(ids []uint64) {
+			defer wg.Done()
+			reconcileCredential(ids, func(outcome reconcileOutcome) {
+				mu.Lock()
+				defer mu.Unlock()
+				if outcome.checked {
+					run.Checked++
+				}
+				if outcome.corrected {
+					run.Corrected++
+				}
+				if outcome.discrepancy {
+					run.Discrepancies++
+				}
+				if outcome.failed {
+					run.Failed++
+				}
+			}, run.ID)
+		}(ids)
+	}
+	wg.Wait()
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+	return quietDB().Model(&db_var.ReconciliationRunT{}).Where("id = ?", run.ID).Updates(map[string]interface{}{
+		"checked":       run.Checked,
+		"corrected":     run.Corrected,
+		"discrepancies": run.Discrepancies,
+		"failed":        run.Failed,
+		"finished_at":   run.FinishedAt,
+	}).Error
+}
+
+type reconcileOutcome struct {
+	checked     bool
+	corrected   bool
+	discrepancy bool
+	failed      bool
+}
+
+// reconcileCredential checks the transactions of one credential, keeping to
+// the per credential rate and concurrency limits.
+func reconcileCredential(ids []uint64, record func(reconcileOutcome), runID uint64) {
+	limiter := time.NewTicker(time.Duration(float64(time.Second) / ReconcileRate))
+	defer limiter.Stop()
+
+	slots := make(chan struct{}, ReconcileConcurrency)
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		<-limiter.C
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(id uint64) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			outcome, err := reconcileTransaction(runID, id, time.Now())
+			if err != nil {
+				logger.Error("Failed to reconcile transaction", zap.Uint64("transaction_id", id), zap.Error(err))
+				outcome.failed = true
+			}
+			record(outcome)
+		}(id)
+	}
+	wg.Wait()
+}
+
+// reconcileTransaction compares one unsettled transaction with the vendor, fixes
+// the stored status when the vendor has a final answer and records every
+// discrepancy in the run report.
+func reconcileTransaction(runID uint64, id uint64, now time.Time) (reconcileOutcome, error) {
+	var outcome reconcileOutcome
+	err := quietDB().Transaction(func(tx *gorm.DB) error {
+		transaction, err := lockTransaction(id, reconcileTxStatuses, tx)
+		if err != nil || transaction == nil {
+			return err
+		}
+		outcome.checked = true
+
+		item := db_var.ReconciliationItemT{
+			RunID:            runID,
+			OrganizationCode: transaction.OrganizationCode,
+			VendorCode:       transaction.Vendor,
+			OrderID:          transaction.OrderID,
+			StoredStatus:     transaction.Status,
+			StoredAmount:     transaction.Amount,
+		}
+
+		PG, credential, err := transactionProvider(*transaction, tx)
+		if err == nil {
+			Status, statusErr := PG.GetStatus(*transaction, credential)
+			if errors.Is(statusErr, provider.ErrTransactionNotFound) {
+				// The customer never opened the payment page or the request never
+				// reached the vendor, the stored status stands
+				Status, statusErr = &provider.TransactionStatus{OrderID: transaction.OrderID, Status: transaction.Status}, nil
+			}
+			if statusErr != nil {
+				err = fmt.Errorf("vendor status lookup: %w", statusErr)
+			} else {
+				item.VendorStatus = Status.VendorStatus
+				item.CanonicalStatus = Status.Status
+				item.VendorAmount = Status.GrossAmount
+
+				switch {
+				case !amountMatches(Status.GrossAmount, transaction.Amount):
+					// Never act on a vendor answer for a different amount
+					item.Action = global_var.ReconcileActionReported
+					item.Detail = "vendor amount does not match stored amount"
+				case Status.Status == global_var.TxStatusRefunded, Status.Status == global_var.TxStatusPartiallyRefunded:
+					item.Action = global_var.ReconcileActionReported
+					item.Detail = "vendor reports a refund for a transaction the bridge never saw paid"
+				default:
+					changed, applyErr := applyVendorStatus(*transaction, credential, Status, reconcilerName, models.StatusCause{
+						Source:     global_var.TxEventSourceReconciler,
+						PayloadRef: fmt.Sprintf("reconciliation_run:%d", runID),
+					}, now, tx)
+					if applyErr != nil {
+						return applyErr
+					}
+					if changed {
+						item.Action = global_var.ReconcileActionCorrected
+						item.Detail = fmt.Sprintf("status corrected from %s to %s", transaction.Status, Status.Status)
+						outcome.corrected = true
+					}
+				}
+			}
+		}
+
+		if err != nil {
+			item.Action = global_var.ReconcileActionError
+			item.Detail = err.Error()
+			outcome.failed = true
+		} else if item.Action != "" {
+			outcome.discrepancy = true
+		}
+
+		if item.Action != "" {
+			if err := tx.Create(&item).Error; err != nil {
+				return err
+			}
+		}
+
+		// UpdateColumn leaves updated_at alone, a check is not a change
+		return tx.Model(&db_var.PaymentGatewayTransactionT{}).Where("id = ?", transaction.ID).UpdateColumn("reconciled_at", now).Error
+	})
+	return outcome, err
+}
+
+// amountMatches reports whether a vendor gross amount equals the stored
+// amount. Vendors that do not report an amount match.
+func amountMatches(GrossAmount string, Amount int) bool {
+	if GrossAmount == "" {
+		return true
+	}
+	value, err := strconv.ParseFloat(GrossAmount, 64)
+	if err != nil {
+		return false
+	}
+	return math.Abs(value-float64(Amount)) <= 0.001
+}
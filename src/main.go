@@ -3,8 +3,10 @@ package main
 import (
 	"pg_bridge_go/config"
 	"pg_bridge_go/database"
+	"pg_bridge_go/jobs"
 	"pg_bridge_go/logger"
 	"pg_bridge_go/routes"
+	"pg_bridge_go/webhook"
 )
 
 func init() {
@@ -16,6 +18,10 @@ func init() {
 	logger.Info("Initializing environment configuration")
 	config.InitEnvConfig()
 
+	// Load the encryption key, the database setup encrypts backfilled secrets
+	logger.Info("Loading encryption key")
+	config.LoadEncryptionKey()
+
 	// initialize SetupDatabase
 	logger.Info("Setting up database")
 	database.SetupDatabase()
@@ -24,13 +30,21 @@ func init() {
 	logger.Info("Load default callback url")
 	config.LoadDefaultCallbackUrl()
 
-	// Load the encryption key
-	logger.Info("Loading encryption key")
-	config.LoadEncryptionKey()
-
 	// Load App Port
 	logger.Info("Loading application port")
 	config.LoadAppPort()
+
+	// Load webhook worker count
+	logger.Info("Loading webhook worker count")
+	config.LoadWebhookWorkers()
+
+	// Load expiry sweep interval
+	logger.Info("Loading expiry sweep interval")
+	config.LoadExpirySweepInterval()
+
+	// Load reconciler settings
+	logger.Info("Loading reconciler settings")
+	config.LoadReconcileConfig()
 }
 
 // Entrypoint for app fiber.
@@ -38,6 +52,17 @@ func main() {
 	// Load the routes
 	r := routes.SetupRouter()
 
+	// Deliver queued merchant webhooks
+	webhook.StartWorkers(config.WebhookWorkers)
+
+	// Expire overdue pending transactions
+	jobs.StartExpirySweeper(config.ExpirySweepInterval)
+
+	// Repair statuses whose notifications never arrived
+	jobs.ReconcileConcurrency = config.ReconcileConcurrency
+	jobs.ReconcileRate = config.ReconcileRate
+	jobs.StartReconciler(config.ReconcileInterval)
+
 	// Start the HTTP API
 	r.Listen("0.0.0.0:" + config.AppPort)
 }
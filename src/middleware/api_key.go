@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/helper"
+	"pg_bridge_go/models"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	AuthTypeBasic  = "basic"
+	AuthTypeAPIKey = "api_key"
+)
+
+// MerchantAuthMiddleware accepts Basic auth, a Bearer API key or an HMAC signed request
+func MerchantAuthMiddleware() fiber.Handler {
+	basicAuth := BasicAuthMiddleware()
+	apiKeyAuth := APIKeyAuthMiddleware()
+	signedAuth := SignedRequestAuthMiddleware()
+
+	return func(c *fiber.Ctx) error {
+		if c.Get(HeaderSignature) != "" {
+			return signedAuth(c)
+		}
+		if strings.HasPrefix(c.Get("Authorization"), "Bearer ") {
+			return apiKeyAuth(c)
+		}
+		return basicAuth(c)
+	}
+}
+
+// APIKeyAuthMiddleware authenticates "Authorization: Bearer pgb_..." API keys
+func APIKeyAuthMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		clientIP := c.IP()
+
+		// Check rate limiting
+		if authRateLimiter.isRateLimited(clientIP) {
+			return helper.SendResponse(fiber.StatusTooManyRequests, "Too many authentication attempts. Please try again later.", nil, c)
+		}
+
+		key := strings.TrimPrefix(c.Get("Authorization"), "Bearer ")
+		lookup, err := helper.ParseAPIKey(key)
+		if err != nil {
+			authRateLimiter.recordAttempt(clientIP)
+			return helper.SendResponse(fiber.StatusUnauthorized, "Not Authorized", nil, c)
+		}
+
+		apiKey, err := loadActiveAPIKey(lookup)
+		if err != nil {
+			authRateLimiter.recordAttempt(clientIP)
+			return helper.SendResponse(fiber.StatusUnauthorized, "Not Authorized", nil, c)
+		}
+
+		if subtle.ConstantTimeCompare([]byte(helper.HashAPIKey(key)), []byte(apiKey.KeyHash)) != 1 {
+			authRateLimiter.recordAttempt(clientIP)
+			return helper.SendResponse(fiber.StatusUnauthorized, "Not Authorized", nil, c)
+		}
+
+		user, scopes, err := effectiveScopes(apiKey)
+		if err != nil {
+			return helper.SendResponse(fiber.StatusUnauthorized, "Not Authorized", nil, c)
+		}
+
+		if err := setAuthLocals(c, user, AuthTypeAPIKey, scopes); err != nil {
+			return helper.SendResponse(fiber.StatusForbidden, "User has no organization", nil, c)
+		}
+
+		global_var.DB.Model(apiKey).Update("last_used_at", time.Now())
+		return c.Next()
+	}
+}
+
+// RequireScope lets the request through when the caller holds any of the scopes.
+// Password users hold the scopes of their role, keys the granted scopes their
+// owner's role still allows.
+func RequireScope(anyOf ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		scopes, _ := c.Locals("scopes").([]string)
+		for _, scope := range anyOf {
+			if slices.Contains(scopes, scope) {
+				return c.Next()
+			}
+		}
+		return helper.SendResponse(fiber.StatusForbidden, "Missing permission "+strings.Join(anyOf, " or "), nil, c)
+	}
+}
+
+// effectiveScopes returns the key owner and the key scopes their role still allows
+func effectiveScopes(apiKey *db_var.APIKeyT) (*db_var.UserT, []string, error) {
+	var user db_var.UserT
+	if err := global_var.DB.First(&user, apiKey.UserID).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var scopes []string
+	for _, scope := range strings.Split(apiKey.Scopes, ",") {
+		if slices.Contains(global_var.RolePermissions[user.Role], scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return &user, scopes, nil
+}
+
+// setAuthLocals stores the verified caller, its organization and permissions
+// in the context. It is only called once authentication succeeded.
+func setAuthLocals(c *fiber.Ctx, user *db_var.UserT, authType string, scopes []string) error {
+	organization, err := models.GetUserOrganizationCode(user.ID, global_var.DB)
+	if err != nil {
+		return err
+	}
+
+	c.Locals("username", user.Username)
+	c.Locals("role", user.Role)
+	c.Locals("organization", organization)
+	c.Locals("auth_type", authType)
+	c.Locals("scopes", scopes)
+	return nil
+}
+
+// loadActiveAPIKey returns the unrevoked, unexpired API key with the lookup prefix
+func loadActiveAPIKey(prefix string) (*db_var.APIKeyT, error) {
+	var apiKey db_var.APIKeyT
+	if err := global_var.DB.Where("prefix = ? AND revoked_at IS NULL", prefix).First(&apiKey).Error; err != nil {
+		return nil, err
+	}
+	if apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("api key expired")
+	}
+	return &apiKey, nil
+}
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"encoding/base64"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
 	"pg_bridge_go/helper"
 	"strings"
 	"sync"
@@ -61,39 +63,39 @@ func init() {
 	initializeCredentialsFromEnv()
 }
 
-// BasicAuthMiddleware is the middleware for basic authentication
-// This version only validates the format but doesn't check credentials
-// Use BasicAuthMiddlewareAdmin for actual credential validation
+// BasicAuthMiddleware is the middleware for basic authentication of merchant users
+// Credentials are verified against the user table and share the admin rate limiter
 func BasicAuthMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		auth := c.Get("Authorization")
-		if auth == "" {
-			return helper.SendResponse(fiber.StatusUnauthorized, "Not Authorized", nil, c)
+		clientIP := c.IP()
+
+		// Check rate limiting
+		if authRateLimiter.isRateLimited(clientIP) {
+			return helper.SendResponse(fiber.StatusTooManyRequests, "Too many authentication attempts. Please try again later.", nil, c)
 		}
 
-		// Parse basic auth header
-		if !strings.HasPrefix(auth, "Basic ") {
+		username, password, ok := parseBasicAuth(c)
+		if !ok {
+			authRateLimiter.recordAttempt(clientIP)
 			return helper.SendResponse(fiber.StatusUnauthorized, "Not Authorized", nil, c)
 		}
 
-		payload, err := base64.StdEncoding.DecodeString(auth[6:])
-		if err != nil {
-			return helper.SendResponse(fiber.StatusUnauthorized, "Not Authorized", nil, c)
+		// Validate input lengths to prevent potential attacks
+		if len(strings.TrimSpace(username)) == 0 || len(username) > 255 || len(password) > 255 {
+			authRateLimiter.recordAttempt(clientIP)
+			return helper.SendResponse(fiber.StatusUnauthorized, "Invalid credentials", nil, c)
 		}
 
-		pair := strings.SplitN(string(payload), ":", 2)
-		if len(pair) != 2 {
+		user, ok := checkUserCredentials(username, password)
+		if !ok {
+			authRateLimiter.recordAttempt(clientIP)
 			return helper.SendResponse(fiber.StatusUnauthorized, "Not Authorized", nil, c)
 		}
 
-		username := pair[0]
-		// Validate username format (basic security check)
-		if len(strings.TrimSpace(username)) == 0 {
-			return helper.SendResponse(fiber.StatusUnauthorized, "Invalid credentials", nil, c)
+		// Store the user in context only once the password is verified
+		if err := setAuthLocals(c, user, AuthTypeBasic, global_var.RolePermissions[user.Role]); err != nil {
+			return helper.SendResponse(fiber.StatusForbidden, "User has no organization", nil, c)
 		}
-
-		// Store username in context for later use
-		c.Locals("username", username)
 		return c.Next()
 	}
 }
@@ -108,32 +110,12 @@ func BasicAuthMiddlewareAdmin() fiber.Handler {
 			return helper.SendResponse(fiber.StatusTooManyRequests, "Too many authentication attempts. Please try again later.", nil, c)
 		}
 
-		auth := c.Get("Authorization")
-		if auth == "" {
-			authRateLimiter.recordAttempt(clientIP)
-			return helper.SendResponse(fiber.StatusUnauthorized, "Not Authorized", nil, c)
-		}
-
-		// Parse basic auth header
-		if !strings.HasPrefix(auth, "Basic ") {
-			authRateLimiter.recordAttempt(clientIP)
-			return helper.SendResponse(fiber.StatusUnauthorized, "Not Authorized", nil, c)
-		}
-
-		payload, err := base64.StdEncoding.DecodeString(auth[6:])
-		if err != nil {
-			authRateLimiter.recordAttempt(clientIP)
-			return helper.SendResponse(fiber.StatusUnauthorized, "Not Authorized", nil, c)
-		}
-
-		pair := strings.SplitN(string(payload), ":", 2)
-		if len(pair) != 2 {
+		username, password, ok := parseBasicAuth(c)
+		if !ok {
 			authRateLimiter.recordAttempt(clientIP)
 			return helper.SendResponse(fiber.StatusUnauthorized, "Not Authorized", nil, c)
 		}
 
-		username, password := pair[0], pair[1]
-		
 		// Validate input lengths to prevent potential attacks
 		if len(username) > 255 || len(password) > 255 {
 			authRateLimiter.recordAttempt(clientIP)
@@ -143,6 +125,11 @@ func BasicAuthMiddlewareAdmin() fiber.Handler {
 		if checkCredentials(username, password) {
 			// Store username in context for later use
 			c.Locals("username", username)
+			c.Locals("role", global_var.RoleAdmin)
+			return c.Next()
+		} else if user, ok := checkUserCredentials(username, password); ok && user.Role == global_var.RoleAdmin {
+			c.Locals("username", username)
+			c.Locals("role", user.Role)
 			return c.Next()
 		} else {
 			authRateLimiter.recordAttempt(clientIP)
@@ -157,10 +144,42 @@ func checkCredentials(username, password string) bool {
 	if !ok {
 		// Always perform a dummy bcrypt operation to prevent timing attacks
 		// even when the username doesn't exist
-		helper.VerifyPassword("dummy", "$2a$10$dummy.hash.to.prevent.timing.attacks.dummy.hash.value")
+		helper.VerifyPassword("dummy", dummyPasswordHash)
 		return false
 	}
 	
 	// Use secure password comparison with bcrypt
 	return secureComparePasswords(password, storedPassHash)
 }
+
+// checkUserCredentials verifies a user against the user table
+func checkUserCredentials(username, password string) (*db_var.UserT, bool) {
+	var user db_var.UserT
+	if err := global_var.DB.Where("username = ?", username).First(&user).Error; err != nil {
+		// Same bcrypt cost whether or not the user exists
+		helper.VerifyPassword(password, dummyPasswordHash)
+		return nil, false
+	}
+
+	return &user, helper.VerifyPassword(password, user.Password)
+}
+
+// parseBasicAuth extracts the username and password from the Authorization header
+func parseBasicAuth(c *fiber.Ctx) (string, string, bool) {
+	auth := c.Get("Authorization")
+	if auth == "" || !strings.HasPrefix(auth, "Basic ") {
+		return "", "", false
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(auth[6:])
+	if err != nil {
+		return "", "", false
+	}
+
+	pair := strings.SplitN(string(payload), ":", 2)
+	if len(pair) != 2 {
+		return "", "", false
+	}
+
+	return pair[0], pair[1], true
+}
@@ -15,6 +15,10 @@ var authorized_credentials = map[string]string{
 	"admin": "$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi", // bcrypt hash of "admin123"
 }
 
+// dummyPasswordHash is a valid bcrypt hash compared against when a user is unknown,
+// so that lookups of missing users cost the same as real ones
+const dummyPasswordHash = "$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi"
+
 // initializeCredentialsFromEnv loads credentials from environment variables if available
 func initializeCredentialsFromEnv() {
 	// Load admin credentials from environment if available
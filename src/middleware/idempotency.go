@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/helper"
+	"pg_bridge_go/logger"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotencyReplayed = "Idempotent-Replayed"
+)
+
+// idempotencyRetention is how long a key and its stored response are kept.
+const idempotencyRetention = 24 * time.Hour
+
+// idempotencyLockTimeout is how long an in progress key blocks retries. A
+// request that crashed without finishing frees its key after this.
+const idempotencyLockTimeout = 2 * time.Minute
+
+// IdempotencyMiddleware makes a route safe to retry. Requests that carry an
+// Idempotency-Key are executed once per organization and key; identical
+// retries get the stored response, a different body with the same key or a
+// retry while the first call is still running gets 409. A server error leaves
+// the key failed instead of storing the response, so a retry runs again with
+// the order id bound to the key (see helper.GetIdempotentOrderIDFiber).
+func IdempotencyMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(HeaderIdempotencyKey)
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > 255 {
+			return helper.SendResponse(fiber.StatusBadRequest, "Idempotency-Key must be at most 255 characters", nil, c)
+		}
+
+		organization := helper.GetOrganizationFiber(c)
+		fingerprint := requestFingerprint(c)
+		now := time.Now()
+
+		global_var.DB.Where("created_at < ?", now.Add(-idempotencyRetention)).Delete(&db_var.IdempotencyKeyT{})
+
+		record := db_var.IdempotencyKeyT{
+			OrganizationCode: organization,
+			Key:              key,
+			Fingerprint:      fingerprint,
+			Status:           global_var.IdempotencyStatusInProgress,
+		}
+		result := global_var.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+		}
+
+		if result.RowsAffected == 0 {
+			var existing db_var.IdempotencyKeyT
+			if err := global_var.DB.Where("organization_code = ? AND key = ?", organization, key).First(&existing).Error; err != nil {
+				return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+			}
+
+			if existing.Fingerprint != fingerprint {
+				return helper.SendResponse(fiber.StatusConflict, "Idempotency-Key was already used with a different request", nil, c)
+			}
+
+			if existing.Status == global_var.IdempotencyStatusCompleted {
+				c.Set(HeaderIdempotencyReplayed, "true")
+				c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+				return c.Status(existing.ResponseCode).SendString(existing.ResponseBody)
+			}
+
+			// Take over a key whose request failed or never finished, otherwise wait
+			claimed := global_var.DB.Model(&db_var.IdempotencyKeyT{}).
+				Where("id = ? AND (status = ? OR (status = ? AND updated_at < ?))", existing.ID,
+					global_var.IdempotencyStatusFailed, global_var.IdempotencyStatusInProgress, now.Add(-idempotencyLockTimeout)).
+				Updates(map[string]interface{}{"status": global_var.IdempotencyStatusInProgress, "updated_at": now})
+			if claimed.Error != nil {
+				return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+			}
+			if claimed.RowsAffected == 0 {
+				c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(idempotencyLockTimeout.Seconds())))
+				return helper.SendResponse(fiber.StatusConflict, "A request with this Idempotency-Key is still in progress", nil, c)
+			}
+			record = existing
+		}
+		c.Locals("idempotency_key_id", record.ID)
+		c.Locals("idempotency_order_id", record.OrderID)
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil || status >= fiber.StatusInternalServerError {
+			// The vendor may have acted before the error, so the key and its
+			// order id stay bound for the retry
+			failErr := global_var.DB.Model(&db_var.IdempotencyKeyT{}).
+				Where("id = ?", record.ID).
+				Update("status", global_var.IdempotencyStatusFailed).Error
+			if failErr != nil {
+				logger.Error("Failed to mark idempotency key failed", zap.String("idempotency_key", key), zap.Error(failErr))
+			}
+			return err
+		}
+
+		saveErr := global_var.DB.Model(&db_var.IdempotencyKeyT{}).
+			Where("id = ?", record.ID).
+			Updates(map[string]interface{}{
+				"status":        global_var.IdempotencyStatusCompleted,
+				"response_code": status,
+				"response_body": string(c.Response().Body()),
+			}).Error
+		if saveErr != nil {
+			logger.Error("Failed to store idempotent response", zap.String("idempotency_key", key), zap.Error(saveErr))
+		}
+		return nil
+	}
+}
+
+// requestFingerprint identifies a request by method, path and body.
+func requestFingerprint(c *fiber.Ctx) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Method() + "\n" + c.Path() + "\n"))
+	hash.Write(c.Body())
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"math"
+	"pg_bridge_go/config"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/helper"
+	"pg_bridge_go/logger"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	AuthTypeSignature = "signature"
+
+	HeaderKeyID     = "X-PGB-Key-Id"
+	HeaderTimestamp = "X-PGB-Timestamp"
+	HeaderNonce     = "X-PGB-Nonce"
+	HeaderSignature = "X-PGB-Signature"
+)
+
+// signatureTolerance is how far the request timestamp may drift from our clock.
+// Nonces are remembered for twice as long so a replay can never outlive them.
+const signatureTolerance = 5 * time.Minute
+
+// SignedRequestAuthMiddleware authenticates requests signed with the HMAC
+// secret of an API key (see helper.SignRequest for the string to sign)
+func SignedRequestAuthMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		clientIP := c.IP()
+
+		// Check rate limiting
+		if authRateLimiter.isRateLimited(clientIP) {
+			return helper.SendResponse(fiber.StatusTooManyRequests, "Too many authentication attempts. Please try again later.", nil, c)
+		}
+
+		keyID := c.Get(HeaderKeyID)
+		timestamp := c.Get(HeaderTimestamp)
+		nonce := c.Get(HeaderNonce)
+		signature := c.Get(HeaderSignature)
+		if keyID == "" || timestamp == "" || nonce == "" || signature == "" || len(nonce) > 64 {
+			authRateLimiter.recordAttempt(clientIP)
+			return helper.SendResponse(fiber.StatusUnauthorized, "Not Authorized", nil, c)
+		}
+
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil || math.Abs(time.Since(time.Unix(unix, 0)).Seconds()) > signatureTolerance.Seconds() {
+			authRateLimiter.recordAttempt(clientIP)
+			return helper.SendResponse(fiber.StatusUnauthorized, "Stale or invalid timestamp", nil, c)
+		}
+
+		apiKey, err := loadActiveAPIKey(keyID)
+		if err != nil || apiKey.SigningSecret == "" {
+			authRateLimiter.recordAttempt(clientIP)
+			return helper.SendResponse(fiber.StatusUnauthorized, "Not Authorized", nil, c)
+		}
+
+		secret, err := helper.Decrypt(apiKey.SigningSecret, config.MasterKey)
+		if err != nil {
+			return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+		}
+
+		expected := helper.SignRequest(secret, c.Method(), c.OriginalURL(), timestamp, nonce, c.Body())
+		if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+			authRateLimiter.recordAttempt(clientIP)
+			return helper.SendResponse(fiber.StatusUnauthorized, "Invalid signature", nil, c)
+		}
+
+		// Only valid signatures reach here, so a duplicate nonce is a replay
+		now := time.Now()
+		if err := global_var.DB.Where("key_prefix = ? AND created_at < ?", apiKey.Prefix, now.Add(-2*signatureTolerance)).Delete(&db_var.RequestNonceT{}).Error; err != nil {
+			logger.Error("Failed to clean up expired nonces", zap.String("key_prefix", apiKey.Prefix), zap.Error(err))
+		}
+		result := global_var.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&db_var.RequestNonceT{KeyPrefix: apiKey.Prefix, Nonce: nonce})
+		if result.Error != nil {
+			logger.Error("Failed to store request nonce", zap.String("key_prefix", apiKey.Prefix), zap.Error(result.Error))
+			return helper.SendResponse(fiber.StatusInternalServerError, "", nil, c)
+		}
+		if result.RowsAffected == 0 {
+			return helper.SendResponse(fiber.StatusUnauthorized, "Replayed request", nil, c)
+		}
+
+		user, scopes, err := effectiveScopes(apiKey)
+		if err != nil {
+			return helper.SendResponse(fiber.StatusUnauthorized, "Not Authorized", nil, c)
+		}
+
+		if err := setAuthLocals(c, user, AuthTypeSignature, scopes); err != nil {
+			return helper.SendResponse(fiber.StatusForbidden, "User has no organization", nil, c)
+		}
+
+		global_var.DB.Model(apiKey).Update("last_used_at", now)
+		return c.Next()
+	}
+}
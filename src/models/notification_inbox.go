@@ -0,0 +1,118 @@
+package models
+
+import (
+	"fmt"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/provider"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func InsertNotificationInbox(inbox *db_var.NotificationInboxT, tx *gorm.DB) error {
+	return tx.Create(inbox).Error
+}
+
+// UpdateNotificationInboxResult records how a stored notification was
+// processed. A replay also bumps replay_count.
+func UpdateNotificationInboxResult(inbox db_var.NotificationInboxT, replay bool, tx *gorm.DB) error {
+	updates := map[string]interface{}{
+		"organization_code":  inbox.OrganizationCode,
+		"order_id":           inbox.OrderID,
+		"verification":       inbox.Verification,
+		"signature_verified": inbox.SignatureVerified,
+		"outcome":            inbox.Outcome,
+		"outcome_detail":     inbox.OutcomeDetail,
+		"response_code":      inbox.ResponseCode,
+		"processed_at":       time.Now(),
+	}
+	if replay {
+		updates["replay_count"] = gorm.Expr("replay_count + 1")
+	}
+
+	return tx.Model(&db_var.NotificationInboxT{}).Where("id = ?", inbox.ID).Updates(updates).Error
+}
+
+// MarkNotificationProcessed records a notification as handled. It reports
+// false when the same vendor transaction and canonical status was handled
+// before.
+func MarkNotificationProcessed(record *db_var.ProcessedNotificationT, tx *gorm.DB) (bool, error) {
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	return result.RowsAffected > 0, result.Error
+}
+
+// IncrementDuplicateNotifications counts a suppressed duplicate on the credential.
+func IncrementDuplicateNotifications(vendorCode string, tx *gorm.DB) error {
+	return tx.Model(&db_var.PaymentGatewayCredentialT{}).
+		Where("code = ?", vendorCode).
+		UpdateColumn("duplicate_notifications", gorm.Expr("duplicate_notifications + 1")).Error
+}
+
+// MigrateProcessedNotificationKey moves processed notifications stored with
+// the raw vendor status key onto the canonical status key. It must run before
+// AutoMigrate builds the new unique index. Legacy rows have no canonical
+// status, so only the newest one per vendor transaction is kept; a resend of
+// an older notification is then applied again, which does not change a
+// transaction already past that status.
+func MigrateProcessedNotificationKey(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&db_var.ProcessedNotificationT{}) || migrator.HasColumn(&db_var.ProcessedNotificationT{}, "Status") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Migrator().AddColumn(&db_var.ProcessedNotificationT{}, "Status"); err != nil {
+			return err
+		}
+
+		table := fmt.Sprintf("%q", db_var.TableName.ProcessedNotifications)
+		err := tx.Exec(`DELETE FROM ` + table + ` legacy USING ` + table + ` newer
+			WHERE legacy.vendor_code = newer.vendor_code
+			AND legacy.transaction_id = newer.transaction_id
+			AND legacy.id < newer.id`).Error
+		if err != nil {
+			return err
+		}
+
+		if !tx.Migrator().HasIndex(&db_var.ProcessedNotificationT{}, "idx_processed_notification") {
+			return nil
+		}
+		return tx.Migrator().DropIndex(&db_var.ProcessedNotificationT{}, "idx_processed_notification")
+	})
+}
+
+// MigrateNotificationInboxHeaders redacts the secret headers of notifications
+// stored before they were redacted on arrival, and carries their verification
+// over to signature_verified so they can still be replayed. It must run
+// before AutoMigrate adds signature_verified.
+func MigrateNotificationInboxHeaders(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&db_var.NotificationInboxT{}) || migrator.HasColumn(&db_var.NotificationInboxT{}, "SignatureVerified") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Migrator().AddColumn(&db_var.NotificationInboxT{}, "SignatureVerified"); err != nil {
+			return err
+		}
+
+		err := tx.Model(&db_var.NotificationInboxT{}).
+			Where("verification = ?", global_var.NotificationVerificationVerified).
+			UpdateColumn("signature_verified", true).Error
+		if err != nil {
+			return err
+		}
+
+		for _, name := range provider.RedactedNotificationHeaders {
+			err := tx.Model(&db_var.NotificationInboxT{}).
+				Where("jsonb_exists(headers, ?)", name).
+				UpdateColumn("headers", gorm.Expr(`jsonb_set(headers, ARRAY[?]::text[], '["[redacted]"]'::jsonb)`, name)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"fmt"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// GetUserOrganizationCode returns the organization the user is a member of
+func GetUserOrganizationCode(userID uint, tx *gorm.DB) (string, error) {
+	var member db_var.OrganizationMemberT
+	if err := tx.Where("user_id = ?", userID).First(&member).Error; err != nil {
+		return "", err
+	}
+	return member.OrganizationCode, nil
+}
+
+// AddOrganizationMember puts the user in the organization, moving them out of
+// any organization they belonged to before.
+func AddOrganizationMember(organizationCode string, user db_var.UserT, createdBy string, tx *gorm.DB) error {
+	if err := tx.Where("user_id = ?", user.ID).Delete(&db_var.OrganizationMemberT{}).Error; err != nil {
+		return err
+	}
+
+	member := db_var.OrganizationMemberT{
+		OrganizationCode: organizationCode,
+		UserID:           user.ID,
+		Username:         user.Username,
+		CreatedBy:        createdBy,
+	}
+	return tx.Create(&member).Error
+}
+
+// CreatePersonalOrganization creates an organization named after the user and
+// makes the user its only member.
+func CreatePersonalOrganization(user db_var.UserT, createdBy string, tx *gorm.DB) error {
+	organization := db_var.OrganizationT{
+		Code:      user.Username,
+		Name:      user.Username,
+		CreatedBy: createdBy,
+	}
+	if err := tx.Create(&organization).Error; err != nil {
+		return err
+	}
+	return AddOrganizationMember(organization.Code, user, createdBy, tx)
+}
+
+// MigrateUserCodeOwnership moves rows owned by a bare user_code into
+// organizations. On the first start with organizations, when none exist yet,
+// every user gets a personal organization whose code is their username, so
+// existing user_code values map one to one. Later starts only move legacy
+// rows; a member removed from an organization is never added back.
+func MigrateUserCodeOwnership(db *gorm.DB) error {
+	users := fmt.Sprintf("%q", db_var.TableName.User)
+	organizations := fmt.Sprintf("%q", db_var.TableName.Organizations)
+	members := fmt.Sprintf("%q", db_var.TableName.OrgMembers)
+	credentials := fmt.Sprintf("%q", db_var.TableName.PGCredentials)
+	transactions := fmt.Sprintf("%q", db_var.TableName.PGTransactions)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&db_var.OrganizationT{}).Count(&existing).Error; err != nil {
+			return err
+		}
+
+		statements := []string{
+			`INSERT INTO ` + organizations + ` (code, name, created_at, created_by)
+			SELECT owner, owner, now(), 'migration' FROM (
+				SELECT user_code AS owner FROM ` + credentials + ` WHERE organization_code IS NULL OR organization_code = ''
+				UNION SELECT user_code FROM ` + transactions + ` WHERE organization_code IS NULL OR organization_code = ''
+			) owners WHERE owner <> ''
+			ON CONFLICT (code) DO NOTHING`,
+		}
+		if existing == 0 {
+			statements = append(statements,
+				`INSERT INTO `+organizations+` (code, name, created_at, created_by)
+				SELECT username, username, now(), 'migration' FROM `+users+`
+				WHERE username <> ''
+				ON CONFLICT (code) DO NOTHING`,
+				`INSERT INTO `+members+` (organization_code, user_id, username, created_at, created_by)
+				SELECT username, id, username, now(), 'migration' FROM `+users+`
+				WHERE username <> ''
+				ON CONFLICT (user_id) DO NOTHING`,
+			)
+		}
+		statements = append(statements,
+			`UPDATE `+credentials+` SET organization_code = user_code WHERE organization_code IS NULL OR organization_code = ''`,
+			`UPDATE `+transactions+` SET organization_code = user_code WHERE organization_code IS NULL OR organization_code = ''`,
+		)
+
+		for _, statement := range statements {
+			result := tx.Exec(statement)
+			if result.Error != nil {
+				logger.Error("Organization migration failed", zap.Error(result.Error))
+				return result.Error
+			}
+		}
+		return nil
+	})
+}
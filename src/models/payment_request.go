@@ -1,14 +1,33 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
 	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
 	"pg_bridge_go/logger"
 	"time"
 
 	"go.uber.org/zap"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// OrderIDSequence backs the {seq} order id placeholder. A database sequence
+// never hands out the same value twice, even across replicas.
+const OrderIDSequence = "order_id_seq"
+
+func CreateOrderIDSequence(db *gorm.DB) error {
+	return db.Exec("CREATE SEQUENCE IF NOT EXISTS " + OrderIDSequence).Error
+}
+
+func NextOrderIDSequence(tx *gorm.DB) (int64, error) {
+	var seq int64
+	err := tx.Raw("SELECT nextval(?)", OrderIDSequence).Scan(&seq).Error
+	return seq, err
+}
+
 func SavePGTransaction(db *gorm.DB, tx *db_var.PaymentGatewayTransactionT) error {
 	if err := db.Create(tx).Error; err != nil {
 		logger.Error("Failed to save transaction", zap.Error(err))
@@ -17,22 +36,49 @@ func SavePGTransaction(db *gorm.DB, tx *db_var.PaymentGatewayTransactionT) error
 	return nil
 }
 
+// ErrOrderIDExists is returned when a transaction with the order id exists.
+var ErrOrderIDExists = errors.New("order_id already exists")
+
+// InsertPGTransaction stores a new transaction with its creation event. It
+// returns ErrOrderIDExists when the order id is taken, including by a
+// concurrent request.
 func InsertPGTransaction(txData *db_var.PaymentGatewayTransactionT, tx *gorm.DB) error {
-	result := tx.Create(txData)
-	return result.Error
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(txData)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOrderIDExists
+	}
+
+	return InsertTransactionEvent(*txData, "", txData.Status, txData.CreatedBy, StatusCause{Source: global_var.TxEventSourceAPI}, tx)
+}
+
+// BindIdempotencyOrderID stores the order id generated for a request on its
+// Idempotency-Key so retries reuse it.
+func BindIdempotencyOrderID(keyID uint64, orderID string, tx *gorm.DB) error {
+	return tx.Model(&db_var.IdempotencyKeyT{}).Where("id = ?", keyID).UpdateColumn("order_id", orderID).Error
 }
 
-func UpdatePGTransactionStatus(orderID, newStatus, PaymentMethods, updatedBy string, tx *gorm.DB) error {
+func UpdatePGTransactionVendorResult(orderID, vendorReference, qrString string, vendorPayload interface{}, tx *gorm.DB) error {
+	updates := map[string]interface{}{
+		"vendor_reference": vendorReference,
+		"qr_string":        qrString,
+		"updated_at":       time.Now(),
+	}
+
+	if vendorPayload != nil {
+		payload, err := json.Marshal(vendorPayload)
+		if err != nil {
+			return err
+		}
+		updates["vendor_payload"] = datatypes.JSON(payload)
+	}
+
 	result := tx.
 		Model(&db_var.PaymentGatewayTransactionT{}).
 		Where("order_id = ?", orderID).
-		Updates(map[string]interface{}{
-			"payment_methods": PaymentMethods,
-			"status":          newStatus,
-			"updated_by":      updatedBy,
-			"paid_at":         time.Now(),
-			"updated_at":      time.Now(),
-		})
+		Updates(updates)
 
 	return result.Error
 }
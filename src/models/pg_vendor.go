@@ -0,0 +1,43 @@
+package models
+
+import (
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/helper"
+	"pg_bridge_go/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// BackfillWebhookSecrets gives credentials created before webhooks were signed
+// a signing secret, encrypted with key, so their webhooks are never sent
+// unsigned.
+func BackfillWebhookSecrets(db *gorm.DB, key []byte) error {
+	var credentials []db_var.PaymentGatewayCredentialT
+	err := db.Select("id", "code").
+		Where("webhook_secret IS NULL OR webhook_secret = ''").
+		Find(&credentials).Error
+	if err != nil {
+		return err
+	}
+
+	for _, credential := range credentials {
+		secret, err := helper.GenerateSigningSecret()
+		if err != nil {
+			return err
+		}
+		encrypted, err := helper.Encrypt(secret, key)
+		if err != nil {
+			return err
+		}
+
+		err = db.Model(&db_var.PaymentGatewayCredentialT{}).
+			Where("id = ? AND (webhook_secret IS NULL OR webhook_secret = '')", credential.ID).
+			UpdateColumn("webhook_secret", encrypted).Error
+		if err != nil {
+			return err
+		}
+		logger.Info("Backfilled webhook secret", zap.String("vendor_code", credential.Code))
+	}
+	return nil
+}
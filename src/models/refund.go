@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"pg_bridge_go/db_var"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func InsertRefund(refund *db_var.RefundT, tx *gorm.DB) error {
+	return tx.Create(refund).Error
+}
+
+// RefundedAmount sums the refunds of an order that are in one of statuses.
+func RefundedAmount(orderID string, statuses []string, tx *gorm.DB) (int, error) {
+	var total int
+	err := tx.Model(&db_var.RefundT{}).
+		Where("order_id = ? AND status IN ?", orderID, statuses).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// UpdateRefundResult records the vendor answer on a refund.
+func UpdateRefundResult(refundID, status, vendorReference, vendorStatus, failureReason string, vendorPayload interface{}, updatedBy string, tx *gorm.DB) error {
+	updates := map[string]interface{}{
+		"status":           status,
+		"vendor_reference": vendorReference,
+		"vendor_status":    vendorStatus,
+		"failure_reason":   failureReason,
+		"updated_by":       updatedBy,
+		"updated_at":       time.Now(),
+	}
+
+	if vendorPayload != nil {
+		payload, err := json.Marshal(vendorPayload)
+		if err != nil {
+			return err
+		}
+		updates["vendor_payload"] = datatypes.JSON(payload)
+	}
+
+	return tx.Model(&db_var.RefundT{}).Where("refund_id = ?", refundID).Updates(updates).Error
+}
@@ -0,0 +1,202 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"slices"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrUnknownTxStatus   = errors.New("unknown transaction status")
+	ErrIllegalTransition = errors.New("illegal transaction status transition")
+)
+
+// TransitionError is returned when a transaction may not move from its stored
+// status to the requested one. It matches ErrIllegalTransition with errors.Is.
+type TransitionError struct {
+	OrderID string
+	From    string
+	To      string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("transaction %s cannot move from %s to %s", e.OrderID, e.From, e.To)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrIllegalTransition
+}
+
+// txTransitions lists the statuses each canonical status may move to. Expired
+// and cancelled orders can still become paid because the vendor may capture a
+// payment after the bridge closed the order on its own. Failed and refunded
+// are final.
+var txTransitions = map[string][]string{
+	global_var.TxStatusPending: {
+		global_var.TxStatusSent,
+		global_var.TxStatusWaitingPayment,
+		global_var.TxStatusPaid,
+		global_var.TxStatusExpired,
+		global_var.TxStatusFailed,
+		global_var.TxStatusCancelled,
+		global_var.TxStatusError,
+	},
+	global_var.TxStatusSent: {
+		global_var.TxStatusWaitingPayment,
+		global_var.TxStatusPaid,
+		global_var.TxStatusExpired,
+		global_var.TxStatusFailed,
+		global_var.TxStatusCancelled,
+		global_var.TxStatusError,
+	},
+	global_var.TxStatusWaitingPayment: {
+		global_var.TxStatusPaid,
+		global_var.TxStatusExpired,
+		global_var.TxStatusFailed,
+		global_var.TxStatusCancelled,
+	},
+	global_var.TxStatusError: {
+		global_var.TxStatusPaid,
+		global_var.TxStatusExpired,
+		global_var.TxStatusFailed,
+		global_var.TxStatusCancelled,
+	},
+	global_var.TxStatusPaid: {
+		global_var.TxStatusPartiallyRefunded,
+		global_var.TxStatusRefunded,
+	},
+	global_var.TxStatusPartiallyRefunded: {
+		global_var.TxStatusRefunded,
+	},
+	global_var.TxStatusExpired:   {global_var.TxStatusPaid},
+	global_var.TxStatusCancelled: {global_var.TxStatusPaid},
+	global_var.TxStatusFailed:    {},
+	global_var.TxStatusRefunded:  {},
+}
+
+// IsTxStatus reports whether status is one of the canonical TxStatus* values.
+func IsTxStatus(status string) bool {
+	_, ok := txTransitions[status]
+	return ok
+}
+
+// CanTransition reports whether a transaction may move from one canonical
+// status to another. Staying in the same status is not a transition.
+func CanTransition(from, to string) bool {
+	return slices.Contains(txTransitions[from], to)
+}
+
+// StatusCause tells the event log where a status change came from.
+type StatusCause struct {
+	Source       string // global_var.TxEventSource* value
+	VendorStatus string
+	// PayloadRef points at the stored input that caused the change, such as
+	// "refund:RF-..." or "reconciliation_run:12".
+	PayloadRef string
+	Payload    interface{}
+}
+
+// UpdatePGTransactionStatus moves a transaction to newStatus, which must be a
+// canonical TxStatus* value, and appends the change to the event log. The row
+// is locked for the rest of tx. paid_at is only set when the transaction
+// enters paid, and paymentMethods only replaces the stored value when it is
+// not empty. It returns the status the transaction had before; when that
+// already is newStatus nothing is written.
+func UpdatePGTransactionStatus(orderID, newStatus, paymentMethods, updatedBy string, cause StatusCause, tx *gorm.DB) (string, error) {
+	if !IsTxStatus(newStatus) {
+		return "", fmt.Errorf("%w %q", ErrUnknownTxStatus, newStatus)
+	}
+
+	var transaction db_var.PaymentGatewayTransactionT
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "order_id", "organization_code", "status").
+		Where("order_id = ?", orderID).
+		First(&transaction).Error
+	if err != nil {
+		return "", err
+	}
+
+	if transaction.Status == newStatus {
+		return transaction.Status, nil
+	}
+	if !CanTransition(transaction.Status, newStatus) {
+		return transaction.Status, &TransitionError{OrderID: orderID, From: transaction.Status, To: newStatus}
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":     newStatus,
+		"updated_by": updatedBy,
+		"updated_at": now,
+	}
+	if newStatus == global_var.TxStatusPaid {
+		updates["paid_at"] = now
+	}
+	if paymentMethods != "" {
+		updates["payment_methods"] = paymentMethods
+	}
+
+	err = tx.Model(&db_var.PaymentGatewayTransactionT{}).Where("id = ?", transaction.ID).Updates(updates).Error
+	if err != nil {
+		return transaction.Status, err
+	}
+
+	return transaction.Status, InsertTransactionEvent(transaction, transaction.Status, newStatus, updatedBy, cause, tx)
+}
+
+// InsertTransactionEvent appends a status change to the event log.
+func InsertTransactionEvent(transaction db_var.PaymentGatewayTransactionT, fromStatus, toStatus, createdBy string, cause StatusCause, tx *gorm.DB) error {
+	event := db_var.TransactionEventT{
+		TransactionID:    transaction.ID,
+		OrderID:          transaction.OrderID,
+		OrganizationCode: transaction.OrganizationCode,
+		FromStatus:       fromStatus,
+		ToStatus:         toStatus,
+		Source:           cause.Source,
+		VendorStatus:     cause.VendorStatus,
+		PayloadRef:       cause.PayloadRef,
+		CreatedBy:        createdBy,
+	}
+
+	if cause.Payload != nil {
+		payload, err := json.Marshal(cause.Payload)
+		if err != nil {
+			return err
+		}
+		event.Payload = datatypes.JSON(payload)
+	}
+
+	return tx.Create(&event).Error
+}
+
+// NormalizePGTransactionStatuses rewrites statuses stored before the state
+// machine. Only paid transactions were stored with the raw vendor status
+// (settlement, capture, PAID and so on), and paid_at was also set on unpaid
+// transactions.
+func NormalizePGTransactionStatuses(db *gorm.DB) error {
+	canonical := make([]string, 0, len(txTransitions))
+	for status := range txTransitions {
+		canonical = append(canonical, status)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&db_var.PaymentGatewayTransactionT{}).
+			Where("status NOT IN ?", canonical).
+			UpdateColumn("status", global_var.TxStatusPaid).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&db_var.PaymentGatewayTransactionT{}).
+			Where("status NOT IN ? AND paid_at IS NOT NULL", []string{global_var.TxStatusPaid, global_var.TxStatusPartiallyRefunded, global_var.TxStatusRefunded}).
+			UpdateColumn("paid_at", nil).Error
+	})
+}
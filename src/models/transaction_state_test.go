@@ -0,0 +1,63 @@
+package models
+
+import (
+	"errors"
+	"pg_bridge_go/global_var"
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{global_var.TxStatusPending, global_var.TxStatusSent, true},
+		{global_var.TxStatusPending, global_var.TxStatusPaid, true},
+		{global_var.TxStatusSent, global_var.TxStatusWaitingPayment, true},
+		{global_var.TxStatusWaitingPayment, global_var.TxStatusPaid, true},
+		{global_var.TxStatusWaitingPayment, global_var.TxStatusExpired, true},
+		{global_var.TxStatusError, global_var.TxStatusPaid, true},
+		{global_var.TxStatusPaid, global_var.TxStatusPartiallyRefunded, true},
+		{global_var.TxStatusPaid, global_var.TxStatusRefunded, true},
+		{global_var.TxStatusPartiallyRefunded, global_var.TxStatusRefunded, true},
+		// A late capture after the bridge closed the order
+		{global_var.TxStatusExpired, global_var.TxStatusPaid, true},
+		{global_var.TxStatusCancelled, global_var.TxStatusPaid, true},
+
+		{global_var.TxStatusPaid, global_var.TxStatusPending, false},
+		{global_var.TxStatusPaid, global_var.TxStatusWaitingPayment, false},
+		{global_var.TxStatusPaid, global_var.TxStatusExpired, false},
+		{global_var.TxStatusPaid, global_var.TxStatusCancelled, false},
+		{global_var.TxStatusWaitingPayment, global_var.TxStatusSent, false},
+		{global_var.TxStatusWaitingPayment, global_var.TxStatusRefunded, false},
+		{global_var.TxStatusPartiallyRefunded, global_var.TxStatusPaid, false},
+		{global_var.TxStatusExpired, global_var.TxStatusCancelled, false},
+		{global_var.TxStatusFailed, global_var.TxStatusPaid, false},
+		{global_var.TxStatusRefunded, global_var.TxStatusPaid, false},
+		{global_var.TxStatusPaid, global_var.TxStatusPaid, false},
+		{global_var.TxStatusPending, "settlement", false},
+		{"settlement", global_var.TxStatusPaid, false},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestTxTransitionsOnlyUseCanonicalStatuses(t *testing.T) {
+	for from, targets := range txTransitions {
+		for _, to := range targets {
+			if !IsTxStatus(to) {
+				t.Errorf("%s may move to %q, which is not a canonical status", from, to)
+			}
+		}
+	}
+}
+
+func TestTransitionErrorUnwraps(t *testing.T) {
+	var err error = &TransitionError{OrderID: "ORDER-1", From: global_var.TxStatusPaid, To: global_var.TxStatusPending}
+	if !errors.Is(err, ErrIllegalTransition) {
+		t.Errorf("errors.Is(%v, ErrIllegalTransition) = false", err)
+	}
+}
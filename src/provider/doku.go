@@ -0,0 +1,303 @@
+package provider
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"pg_bridge_go/config"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/helper"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type dokuProvider struct{}
+
+func init() {
+	Register(global_var.PGVendor.Doku, dokuProvider{})
+}
+
+func (dokuProvider) Name() string {
+	return "doku"
+}
+
+// DOKU invoice_number: up to 64 characters of letters, digits and - _
+var dokuOrderIDRules = OrderIDRules{
+	MaxLength: 64,
+	Pattern:   regexp.MustCompile(`^[A-Za-z0-9\-_]+$`),
+	Allowed:   "letters, digits and - _",
+}
+
+func (dokuProvider) OrderIDRules() OrderIDRules {
+	return dokuOrderIDRules
+}
+
+// DokuSignature builds the Signature header value DOKU expects. The digest line
+// is only part of the component when the request has a body.
+func DokuSignature(clientID, requestID, timestamp, target string, body []byte, secret string) string {
+	component := "Client-Id:" + clientID + "\n" +
+		"Request-Id:" + requestID + "\n" +
+		"Request-Timestamp:" + timestamp + "\n" +
+		"Request-Target:" + target
+	if len(body) > 0 {
+		digest := sha256.Sum256(body)
+		component += "\nDigest:" + base64.StdEncoding.EncodeToString(digest[:])
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(component))
+	return "HMACSHA256=" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// dokuKeys returns the decrypted Client-Id (MerchantID) and secret key (APISecret).
+func dokuKeys(Vendor db_var.PaymentGatewayCredentialT) (string, string, error) {
+	ClientID, err := helper.Decrypt(Vendor.MerchantID, config.MasterKey)
+	if err != nil {
+		return "", "", err
+	}
+	SecretKey, err := helper.Decrypt(Vendor.APISecret, config.MasterKey)
+	if err != nil {
+		return "", "", err
+	}
+	if ClientID == "" || SecretKey == "" {
+		return "", "", fmt.Errorf("doku credential requires merchant_id and api_secret")
+	}
+	return ClientID, SecretKey, nil
+}
+
+// SendDokuRequest signs and sends a request to the DOKU API and decodes a
+// successful response into out.
+func SendDokuRequest(Method, Target string, Body interface{}, Vendor db_var.PaymentGatewayCredentialT, out interface{}) error {
+	UrlEnvMode := global_var.PGUrlList.Doku.Dev
+	if Vendor.Mode == "prod" {
+		UrlEnvMode = global_var.PGUrlList.Doku.Prod
+	}
+
+	ClientID, SecretKey, err := dokuKeys(Vendor)
+	if err != nil {
+		return err
+	}
+
+	// The digest has to cover the exact bytes sent, so marshal once up front
+	var BodyBytes []byte
+	if Body != nil {
+		BodyBytes, err = json.Marshal(Body)
+		if err != nil {
+			return err
+		}
+	}
+
+	RequestID := uuid.NewString()
+	Timestamp := time.Now().UTC().Format("2006-01-02T15:04:05Z")
+
+	Reqs := helper.RequestOptions{
+		Method:      Method,
+		URL:         UrlEnvMode + Target,
+		ContentType: "application/json",
+		Headers: map[string]string{
+			"Client-Id":         ClientID,
+			"Request-Id":        RequestID,
+			"Request-Timestamp": Timestamp,
+			"Signature":         DokuSignature(ClientID, RequestID, Timestamp, Target, BodyBytes, SecretKey),
+		},
+	}
+	if BodyBytes != nil {
+		Reqs.Body = json.RawMessage(BodyBytes)
+	}
+
+	Result, HttpStatus, _, err := helper.SendRequest(Reqs)
+	if err != nil {
+		return err
+	}
+
+	resMap, ok := Result.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected response format from DOKU")
+	}
+
+	jsonBytes, err := json.Marshal(resMap)
+	if err != nil {
+		return fmt.Errorf("failed to re-marshal result: %w", err)
+	}
+
+	if HttpStatus < 200 || HttpStatus >= 300 {
+		var errRes global_var.DOKU_ErrorBody
+		if err := json.Unmarshal(jsonBytes, &errRes); err == nil {
+			if errRes.Error.Message != "" {
+				return fmt.Errorf("doku error: %s (%s)", errRes.Error.Message, errRes.Error.Code)
+			}
+			if len(errRes.Message) > 0 {
+				return fmt.Errorf("doku error: %s", strings.Join(errRes.Message, "; "))
+			}
+		}
+		return fmt.Errorf("doku returned HTTP %d but error message could not be parsed", HttpStatus)
+	}
+
+	if err := json.Unmarshal(jsonBytes, out); err != nil {
+		return fmt.Errorf("failed to unmarshal to success struct: %w", err)
+	}
+	return nil
+}
+
+func (dokuProvider) CreatePayment(Req PaymentRequest, credential db_var.PaymentGatewayCredentialT) (*PaymentResult, error) {
+	// DOKU does not append anything to the redirect, so the order has to be in it
+	RedirectURL, err := url.Parse(FinishRedirectURL(Req, credential))
+	if err != nil {
+		return nil, err
+	}
+	Query := RedirectURL.Query()
+	Query.Set("order_id", Req.OrderID)
+	RedirectURL.RawQuery = Query.Encode()
+
+	RequestBody := global_var.DOKU_RequestBody{
+		Order: global_var.DOKU_Order{
+			Amount:        Req.Amount,
+			InvoiceNumber: Req.OrderID,
+			Currency:      CurrencyOrDefault(Req, "IDR"),
+			CallbackURL:   RedirectURL.String(),
+		},
+	}
+
+	if Req.Items != nil {
+		for _, v := range *Req.Items {
+			RequestBody.Order.LineItems = append(RequestBody.Order.LineItems, global_var.DOKU_LineItem{
+				Name:     v.Name,
+				Price:    v.Price,
+				Quantity: v.Quantity,
+			})
+		}
+	}
+
+	if Req.Customer != nil {
+		RequestBody.Customer = &global_var.DOKU_Customer{
+			Name:  strings.TrimSpace(Req.Customer.FirstName + " " + Req.Customer.LastName),
+			Email: Req.Customer.Email,
+			Phone: Req.Customer.Phone,
+		}
+	}
+
+	Payment := global_var.DOKU_Payment{}
+	if Req.Expiry != nil {
+		now := time.Now()
+		if ExpiresAt, ok := Req.Expiry.Deadline(now); ok {
+			// payment_due_date is expressed in minutes
+			Payment.PaymentDueDate = int(ExpiresAt.Sub(now).Minutes())
+		}
+	}
+	if Req.EnabledPayments != nil {
+		Payment.PaymentMethodTypes = *Req.EnabledPayments
+	}
+	if Payment.PaymentDueDate > 0 || len(Payment.PaymentMethodTypes) > 0 {
+		RequestBody.Payment = &Payment
+	}
+
+	var Result global_var.DOKU_ResultBody
+	if err := SendDokuRequest("POST", "/checkout/v1/payment", RequestBody, credential, &Result); err != nil {
+		return nil, err
+	}
+
+	return &PaymentResult{
+		RedirectURL:     Result.Response.Payment.URL,
+		VendorReference: Result.Response.Payment.TokenID,
+		VendorPayload:   Result,
+	}, nil
+}
+
+func (dokuProvider) GetStatus(transaction db_var.PaymentGatewayTransactionT, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error) {
+	var Result global_var.DOKU_StatusBody
+	if err := SendDokuRequest("GET", "/orders/v1/status/"+url.PathEscape(transaction.OrderID), nil, credential, &Result); err != nil {
+		return nil, err
+	}
+	return dokuTransactionStatus(Result), nil
+}
+
+func (dokuProvider) ParseNotification(req NotificationRequest, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error) {
+	if !req.Verified {
+		if err := verifyDokuNotification(req, credential); err != nil {
+			return nil, err
+		}
+	}
+
+	var Notification global_var.DOKU_StatusBody
+	if err := json.Unmarshal(req.Body, &Notification); err != nil {
+		return nil, err
+	}
+	return dokuTransactionStatus(Notification), nil
+}
+
+// dokuTimestampTolerance is how far the Request-Timestamp of a notification
+// may drift from our clock, so a captured notification cannot be replayed.
+const dokuTimestampTolerance = 5 * time.Minute
+
+// verifyDokuNotification checks the Client-Id, Request-Timestamp and Signature
+// headers of a DOKU notification.
+func verifyDokuNotification(req NotificationRequest, credential db_var.PaymentGatewayCredentialT) error {
+	ClientID, SecretKey, err := dokuKeys(credential)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	Timestamp, err := time.Parse(time.RFC3339, req.Headers.Get("Request-Timestamp"))
+	if err != nil {
+		return fmt.Errorf("%w: invalid Request-Timestamp", ErrInvalidSignature)
+	}
+	if Drift := time.Since(Timestamp); Drift > dokuTimestampTolerance || Drift < -dokuTimestampTolerance {
+		return fmt.Errorf("%w: Request-Timestamp outside the allowed window", ErrInvalidSignature)
+	}
+
+	if !hmac.Equal([]byte(req.Headers.Get("Client-Id")), []byte(ClientID)) {
+		return ErrInvalidSignature
+	}
+
+	Expected := DokuSignature(ClientID, req.Headers.Get("Request-Id"), req.Headers.Get("Request-Timestamp"), req.Path, req.Body, SecretKey)
+	if !hmac.Equal([]byte(Expected), []byte(req.Headers.Get("Signature"))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (dokuProvider) Refund(req RefundRequest, credential db_var.PaymentGatewayCredentialT) (*RefundResult, error) {
+	return nil, ErrNotSupported
+}
+
+func (dokuProvider) Cancel(transaction db_var.PaymentGatewayTransactionT, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error) {
+	return nil, ErrNotSupported
+}
+
+func (dokuProvider) Expire(transaction db_var.PaymentGatewayTransactionT, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error) {
+	return nil, ErrNotSupported
+}
+
+func dokuTransactionStatus(n global_var.DOKU_StatusBody) *TransactionStatus {
+	Status := global_var.TxStatusPending
+	switch n.Transaction.Status {
+	case "SUCCESS":
+		Status = global_var.TxStatusPaid
+	case "PENDING":
+		Status = global_var.TxStatusWaitingPayment
+	case "FAILED":
+		Status = global_var.TxStatusFailed
+	case "EXPIRED":
+		Status = global_var.TxStatusExpired
+	case "REFUNDED":
+		Status = global_var.TxStatusRefunded
+	}
+
+	return &TransactionStatus{
+		OrderID:       n.Order.InvoiceNumber,
+		TransactionID: n.Transaction.OriginalRequestID,
+		VendorStatus:  n.Transaction.Status,
+		Status:        Status,
+		PaymentType:   strings.ToLower(n.Channel.ID),
+		GrossAmount:   strconv.Itoa(n.Order.Amount),
+		Raw:           n,
+	}
+}
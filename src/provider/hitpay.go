@@ -0,0 +1,281 @@
+package provider
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"pg_bridge_go/config"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/helper"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type hitpayProvider struct{}
+
+func init() {
+	Register(global_var.PGVendor.HitPay, hitpayProvider{})
+}
+
+func (hitpayProvider) Name() string {
+	return "hitpay"
+}
+
+// HitPay reference_number accepts up to 255 characters
+var hitpayOrderIDRules = OrderIDRules{
+	MaxLength: 255,
+	Pattern:   regexp.MustCompile(`^[A-Za-z0-9\-_.~]+$`),
+	Allowed:   "letters, digits and - _ . ~",
+}
+
+func (hitpayProvider) OrderIDRules() OrderIDRules {
+	return hitpayOrderIDRules
+}
+
+// SendHitPayRequest calls the HitPay API with the credential business API key
+// and decodes a successful response into out.
+func SendHitPayRequest(Reqs helper.RequestOptions, Vendor db_var.PaymentGatewayCredentialT, out interface{}) error {
+	UrlEnvMode := global_var.PGUrlList.HitPay.Dev
+	if Vendor.Mode == "prod" {
+		UrlEnvMode = global_var.PGUrlList.HitPay.Prod
+	}
+
+	ApiKeys, err := helper.Decrypt(Vendor.APIKey, config.MasterKey)
+	if err != nil {
+		return err
+	}
+
+	Reqs.URL = UrlEnvMode + Reqs.URL
+	Reqs.ContentType = "application/json"
+	Reqs.Headers = map[string]string{
+		"X-BUSINESS-API-KEY": ApiKeys,
+		"X-Requested-With":   "XMLHttpRequest",
+	}
+
+	Result, HttpStatus, _, err := helper.SendRequest(Reqs)
+	if err != nil {
+		return err
+	}
+
+	resMap, ok := Result.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected response format from HitPay")
+	}
+
+	jsonBytes, err := json.Marshal(resMap)
+	if err != nil {
+		return fmt.Errorf("failed to re-marshal result: %w", err)
+	}
+
+	if HttpStatus < 200 || HttpStatus >= 300 {
+		var errRes global_var.HTPY_ErrorBody
+		if err := json.Unmarshal(jsonBytes, &errRes); err == nil && errRes.Message != "" {
+			return fmt.Errorf("hitpay error: %s", errRes.Message)
+		}
+		return fmt.Errorf("hitpay returned HTTP %d but error message could not be parsed", HttpStatus)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(jsonBytes, out); err != nil {
+		return fmt.Errorf("failed to unmarshal to success struct: %w", err)
+	}
+	return nil
+}
+
+func (hitpayProvider) CreatePayment(Req PaymentRequest, credential db_var.PaymentGatewayCredentialT) (*PaymentResult, error) {
+	// HitPay takes a decimal string, bridge amounts are minor units
+	Currency := CurrencyOrDefault(Req, "SGD")
+	RequestBody := global_var.HTPY_RequestBody{
+		Amount:          FormatMinorAmount(Req.Amount, Currency),
+		Currency:        Currency,
+		ReferenceNumber: Req.OrderID,
+		Purpose:         Req.OrderID,
+		Webhook:         NotificationURL(credential),
+	}
+
+	if Req.Customer != nil {
+		RequestBody.Name = strings.TrimSpace(Req.Customer.FirstName + " " + Req.Customer.LastName)
+		RequestBody.Email = Req.Customer.Email
+		RequestBody.Phone = Req.Customer.Phone
+	}
+
+	if Req.EnabledPayments != nil {
+		RequestBody.PaymentMethods = *Req.EnabledPayments
+	}
+
+	if Req.Expiry != nil {
+		if ExpiresAt, ok := Req.Expiry.Deadline(time.Now()); ok {
+			RequestBody.ExpiryDate = ExpiresAt.Format("2006-01-02 15:04:05")
+		}
+	}
+
+	// HitPay appends reference and status but not our order id key
+	RedirectURL, err := url.Parse(FinishRedirectURL(Req, credential))
+	if err != nil {
+		return nil, err
+	}
+	Query := RedirectURL.Query()
+	Query.Set("order_id", Req.OrderID)
+	RedirectURL.RawQuery = Query.Encode()
+	RequestBody.RedirectURL = RedirectURL.String()
+
+	Reqs := helper.RequestOptions{
+		Method: "POST",
+		URL:    "/v1/payment-requests",
+		Body:   RequestBody,
+	}
+
+	var Result global_var.HTPY_ResultBody
+	if err := SendHitPayRequest(Reqs, credential, &Result); err != nil {
+		return nil, err
+	}
+
+	return &PaymentResult{
+		RedirectURL:     Result.URL,
+		VendorReference: Result.ID,
+		VendorPayload:   Result,
+	}, nil
+}
+
+func (hitpayProvider) GetStatus(transaction db_var.PaymentGatewayTransactionT, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error) {
+	if transaction.VendorReference == "" {
+		return nil, fmt.Errorf("transaction %s has no hitpay reference", transaction.OrderID)
+	}
+
+	Reqs := helper.RequestOptions{
+		Method: "GET",
+		URL:    "/v1/payment-requests/" + transaction.VendorReference,
+	}
+
+	var Result global_var.HTPY_ResultBody
+	if err := SendHitPayRequest(Reqs, credential, &Result); err != nil {
+		return nil, err
+	}
+
+	Status := &TransactionStatus{
+		OrderID:      transaction.OrderID,
+		VendorStatus: Result.Status,
+		Status:       hitpayCanonicalStatus(Result.Status),
+		GrossAmount:  hitpayMinorAmount(Result.Amount, Result.Currency),
+		Raw:          Result,
+	}
+	for _, v := range Result.Payments {
+		if v.Status == "succeeded" || v.Status == "completed" {
+			Status.TransactionID = v.ID
+			Status.PaymentType = v.PaymentType
+			Status.Status = global_var.TxStatusPaid
+		}
+	}
+	return Status, nil
+}
+
+func (hitpayProvider) ParseNotification(req NotificationRequest, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error) {
+	Values, err := url.ParseQuery(string(req.Body))
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.Verified {
+		Salt, err := helper.Decrypt(credential.APISecret, config.MasterKey)
+		if err != nil || Salt == "" {
+			return nil, ErrInvalidSignature
+		}
+
+		if !VerifyHitPayHMAC(Values, Salt) {
+			return nil, ErrInvalidSignature
+		}
+	}
+
+	Raw := map[string]string{}
+	for key := range Values {
+		Raw[key] = Values.Get(key)
+	}
+
+	return &TransactionStatus{
+		OrderID:       Values.Get("reference_number"),
+		TransactionID: Values.Get("payment_id"),
+		VendorStatus:  Values.Get("status"),
+		Status:        hitpayCanonicalStatus(Values.Get("status")),
+		GrossAmount:   hitpayMinorAmount(Values.Get("amount"), Values.Get("currency")),
+		Raw:           Raw,
+	}, nil
+}
+
+func (hitpayProvider) Refund(req RefundRequest, credential db_var.PaymentGatewayCredentialT) (*RefundResult, error) {
+	return nil, ErrNotSupported
+}
+
+func (hitpayProvider) Cancel(transaction db_var.PaymentGatewayTransactionT, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error) {
+	return nil, ErrNotSupported
+}
+
+func (hitpayProvider) Expire(transaction db_var.PaymentGatewayTransactionT, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error) {
+	return nil, ErrNotSupported
+}
+
+// VerifyHitPayHMAC checks the hmac field of a HitPay webhook. HitPay signs the
+// remaining fields sorted by key and concatenated as key+value with the salt.
+func VerifyHitPayHMAC(values url.Values, salt string) bool {
+	Received := values.Get("hmac")
+	if Received == "" {
+		return false
+	}
+
+	var keys []string
+	for key := range values {
+		if key != "hmac" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var payload strings.Builder
+	for _, key := range keys {
+		payload.WriteString(key)
+		payload.WriteString(values.Get(key))
+	}
+
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(payload.String()))
+	Expected := hex.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(Expected), []byte(strings.ToLower(Received)))
+}
+
+// hitpayMinorAmount converts a HitPay decimal amount into the minor units the
+// bridge stores. An amount that does not parse is passed on as is, so it
+// fails the amount checks instead of matching.
+func hitpayMinorAmount(amount, currency string) string {
+	Minor, err := ParseMinorAmount(amount, currency)
+	if err != nil {
+		return amount
+	}
+	return strconv.Itoa(Minor)
+}
+
+func hitpayCanonicalStatus(status string) string {
+	switch status {
+	case "completed", "succeeded":
+		return global_var.TxStatusPaid
+	case "pending":
+		return global_var.TxStatusWaitingPayment
+	case "failed":
+		return global_var.TxStatusFailed
+	case "canceled":
+		return global_var.TxStatusCancelled
+	case "expired":
+		return global_var.TxStatusExpired
+	case "refunded":
+		return global_var.TxStatusRefunded
+	}
+	return global_var.TxStatusPending
+}
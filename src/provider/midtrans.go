@@ -0,0 +1,614 @@
+package provider
+
+import (
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"pg_bridge_go/config"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/helper"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type MidtransNotificationStruct struct {
+	TransactionTime   string `json:"transaction_time"`
+	TransactionStatus string `json:"transaction_status"`
+	TransactionID     string `json:"transaction_id"`
+	StatusMessage     string `json:"status_message"`
+	StatusCode        string `json:"status_code"`
+	SignatureKey      string `json:"signature_key"`
+	SettlementTime    string `json:"settlement_time"`
+	PaymentType       string `json:"payment_type"`
+	OrderID           string `json:"order_id"`
+	MerchantID        string `json:"merchant_id"`
+	GrossAmount       string `json:"gross_amount"`
+	FraudStatus       string `json:"fraud_status"`
+	Currency          string `json:"currency"`
+}
+
+type MidtransTransactionRequest struct {
+	TransactionDetails MidtransTransactionDetails `json:"transaction_details"`
+	ItemDetails        *[]MidtransItemDetail      `json:"item_details,omitempty"`
+	CustomerDetails    *MidtransCustomerDetails   `json:"customer_details,omitempty"`
+	EnabledPayments    *[]string                  `json:"enabled_payments,omitempty"`
+	CreditCard         MidtransCreditCard         `json:"credit_card"`
+	BCAVA              *MidtransBCAVA             `json:"bca_va,omitempty"`
+	BNIVA              *MidtransVABank            `json:"bni_va,omitempty"`
+	PermataVA          *MidtransPermataVA         `json:"permata_va,omitempty"`
+	Callbacks          *MidtransCallbacks         `json:"callbacks,omitempty"`
+	Expiry             *MidtransExpiry            `json:"expiry,omitempty"`
+	CustomField1       *string                    `json:"custom_field1,omitempty"`
+	CustomField2       *string                    `json:"custom_field2,omitempty"`
+	CustomField3       *string                    `json:"custom_field3,omitempty"`
+}
+
+type MidtransTransactionDetails struct {
+	OrderID     string `json:"order_id"`
+	GrossAmount int    `json:"gross_amount"`
+}
+
+type MidtransItemDetail struct {
+	ID           string  `json:"id"`
+	Price        int     `json:"price"`
+	Quantity     int     `json:"quantity"`
+	Name         string  `json:"name"`
+	Brand        *string `json:"brand,omitempty"`
+	Category     *string `json:"category,omitempty"`
+	MerchantName *string `json:"merchant_name,omitempty"`
+}
+
+type MidtransCustomerDetails struct {
+	FirstName       string          `json:"first_name"`
+	LastName        string          `json:"last_name"`
+	Email           string          `json:"email"`
+	Phone           string          `json:"phone"`
+	BillingAddress  MidtransAddress `json:"billing_address"`
+	ShippingAddress MidtransAddress `json:"shipping_address"`
+}
+
+type MidtransAddress struct {
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	Email       string `json:"email"`
+	Phone       string `json:"phone"`
+	Address     string `json:"address"`
+	City        string `json:"city"`
+	PostalCode  string `json:"postal_code"`
+	CountryCode string `json:"country_code"`
+}
+
+type MidtransCreditCard struct {
+	Secure        bool                 `json:"secure"`
+	Bank          *string              `json:"bank,omitempty"`
+	Installment   *MidtransInstallment `json:"installment,omitempty"`
+	WhitelistBins *[]string            `json:"whitelist_bins,omitempty"`
+}
+
+type MidtransInstallment struct {
+	Required bool             `json:"required"`
+	Terms    map[string][]int `json:"terms"`
+}
+
+type MidtransBCAVA struct {
+	VANumber       string               `json:"va_number"`
+	SubCompanyCode string               `json:"sub_company_code"`
+	FreeText       MidtransFreeTextInfo `json:"free_text"`
+}
+
+type MidtransFreeTextInfo struct {
+	Inquiry []MidtransLanguageText `json:"inquiry"`
+	Payment []MidtransLanguageText `json:"payment"`
+}
+
+type MidtransLanguageText struct {
+	EN string `json:"en"`
+	ID string `json:"id"`
+}
+
+type MidtransVABank struct {
+	VANumber string `json:"va_number"`
+}
+
+type MidtransPermataVA struct {
+	VANumber      string `json:"va_number"`
+	RecipientName string `json:"recipient_name"`
+}
+
+type MidtransCallbacks struct {
+	Finish *string `json:"finish,omitempty"`
+}
+
+type MidtransExpiry struct {
+	StartTime *string `json:"start_time,omitempty"`
+	Unit      string  `json:"unit"`
+	Duration  int     `json:"duration"`
+}
+
+type MidtransErrorResponse struct {
+	ErrorMessages []string `json:"error_messages"`
+}
+
+type MidtransSuccessResponse struct {
+	Token       string `json:"token"`
+	RedirectURL string `json:"redirect_url"`
+}
+
+type MidtransRefundRequest struct {
+	RefundKey string `json:"refund_key"`
+	Amount    int    `json:"amount"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+type MidtransRefundResponse struct {
+	StatusCode         string      `json:"status_code"`
+	StatusMessage      string      `json:"status_message"`
+	TransactionID      string      `json:"transaction_id"`
+	OrderID            string      `json:"order_id"`
+	PaymentType        string      `json:"payment_type"`
+	TransactionStatus  string      `json:"transaction_status"`
+	GrossAmount        string      `json:"gross_amount"`
+	RefundChargebackID json.Number `json:"refund_chargeback_id"`
+	RefundAmount       string      `json:"refund_amount"`
+	RefundKey          string      `json:"refund_key"`
+}
+
+func SendRequestPaymentToMidtrans(Data MidtransTransactionRequest, Vendor db_var.PaymentGatewayCredentialT) (string, error) {
+	UrlEnvMode := global_var.PGUrlList.Midtrans.Dev
+	if Vendor.Mode == "prod" {
+		UrlEnvMode = global_var.PGUrlList.Midtrans.Prod
+	}
+
+	ApiKeys, err := helper.Decrypt(Vendor.APIKey, config.MasterKey)
+	if err != nil {
+		return "", err
+	}
+
+	Reqs := helper.RequestOptions{
+		Method:      "POST",
+		URL:         UrlEnvMode + "/snap/v1/transactions",
+		Body:        Data,
+		AuthType:    helper.AuthBasic,
+		Username:    ApiKeys,
+		ContentType: "application/json",
+	}
+
+	Result, HttpStatus, _, err := helper.SendRequest(Reqs)
+	if err != nil {
+		return "", err
+	}
+
+	resMap, ok := Result.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("unexpected response format from Midtrans")
+	}
+
+	jsonBytes, err := json.Marshal(resMap)
+	if err != nil {
+		return "", fmt.Errorf("failed to re-marshal result: %w", err)
+	}
+
+	if HttpStatus < 200 || HttpStatus >= 300 {
+		var errRes MidtransErrorResponse
+		if err := json.Unmarshal(jsonBytes, &errRes); err == nil && len(errRes.ErrorMessages) > 0 {
+			return "", fmt.Errorf("midtrans error: %s", strings.Join(errRes.ErrorMessages, "; "))
+		}
+		return "", fmt.Errorf("midtrans returned HTTP %d but error message could not be parsed", HttpStatus)
+	}
+
+	var midtransRes MidtransSuccessResponse
+	if err := json.Unmarshal(jsonBytes, &midtransRes); err != nil {
+		return "", fmt.Errorf("failed to unmarshal to success struct: %w", err)
+	}
+
+	return midtransRes.RedirectURL, nil
+}
+
+// SendRefundToMidtrans refunds part or all of a settled order. Midtrans answers
+// HTTP 200 for rejected refunds too, so status_code decides the outcome.
+func SendRefundToMidtrans(OrderID string, Data MidtransRefundRequest, Vendor db_var.PaymentGatewayCredentialT) (*MidtransRefundResponse, error) {
+	UrlEnvMode := global_var.PGUrlList.MidtransSend.Dev
+	if Vendor.Mode == "prod" {
+		UrlEnvMode = global_var.PGUrlList.MidtransSend.Prod
+	}
+
+	ApiKeys, err := helper.Decrypt(Vendor.APIKey, config.MasterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	Reqs := helper.RequestOptions{
+		Method:      "POST",
+		URL:         UrlEnvMode + "/v2/" + OrderID + "/refund",
+		Body:        Data,
+		AuthType:    helper.AuthBasic,
+		Username:    ApiKeys,
+		ContentType: "application/json",
+	}
+
+	Result, HttpStatus, _, err := helper.SendRequest(Reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	resMap, ok := Result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected response format from Midtrans")
+	}
+
+	jsonBytes, err := json.Marshal(resMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-marshal result: %w", err)
+	}
+
+	// A 4xx is a refusal; a 5xx may have come after the refund went through
+	if HttpStatus < 200 || HttpStatus >= 300 {
+		var errRes MidtransErrorResponse
+		if err := json.Unmarshal(jsonBytes, &errRes); err == nil && len(errRes.ErrorMessages) > 0 {
+			return nil, midtransRefundError(HttpStatus, fmt.Errorf("midtrans error: %s", strings.Join(errRes.ErrorMessages, "; ")))
+		}
+		return nil, midtransRefundError(HttpStatus, fmt.Errorf("midtrans returned HTTP %d but error message could not be parsed", HttpStatus))
+	}
+
+	var midtransRes MidtransRefundResponse
+	if err := json.Unmarshal(jsonBytes, &midtransRes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal to success struct: %w", err)
+	}
+	if midtransRes.StatusCode != "200" {
+		Code, _ := strconv.Atoi(midtransRes.StatusCode)
+		return nil, midtransRefundError(Code, fmt.Errorf("midtrans error: %s %s", midtransRes.StatusCode, midtransRes.StatusMessage))
+	}
+
+	return &midtransRes, nil
+}
+
+// midtransRefundError marks err as a definite rejection when code is a 4xx.
+func midtransRefundError(code int, err error) error {
+	if code >= 400 && code < 500 {
+		return fmt.Errorf("%w: %w", ErrVendorRejected, err)
+	}
+	return err
+}
+
+// SendCancelToMidtrans cancels an order that has not been paid yet.
+func SendCancelToMidtrans(OrderID string, Vendor db_var.PaymentGatewayCredentialT) (*MidtransNotificationStruct, error) {
+	return sendMidtransStatusAction(OrderID, "cancel", "200", Vendor)
+}
+
+// SendExpireToMidtrans expires a pending order right away. Midtrans reports a
+// successful expiry with status_code 407.
+func SendExpireToMidtrans(OrderID string, Vendor db_var.PaymentGatewayCredentialT) (*MidtransNotificationStruct, error) {
+	return sendMidtransStatusAction(OrderID, "expire", "407", Vendor)
+}
+
+// sendMidtransStatusAction posts to /v2/{order_id}/{action}. Midtrans puts the
+// outcome in status_code, which is 404 when the customer never picked a
+// payment method and the order does not exist on their side yet.
+func sendMidtransStatusAction(OrderID, Action, SuccessCode string, Vendor db_var.PaymentGatewayCredentialT) (*MidtransNotificationStruct, error) {
+	UrlEnvMode := global_var.PGUrlList.MidtransSend.Dev
+	if Vendor.Mode == "prod" {
+		UrlEnvMode = global_var.PGUrlList.MidtransSend.Prod
+	}
+
+	ApiKeys, err := helper.Decrypt(Vendor.APIKey, config.MasterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	Reqs := helper.RequestOptions{
+		Method:   "POST",
+		URL:      UrlEnvMode + "/v2/" + OrderID + "/" + Action,
+		AuthType: helper.AuthBasic,
+		Username: ApiKeys,
+	}
+
+	Result, HttpStatus, _, err := helper.SendRequest(Reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	resMap, ok := Result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected response format from Midtrans")
+	}
+
+	jsonBytes, err := json.Marshal(resMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-marshal result: %w", err)
+	}
+
+	var midtransRes MidtransNotificationStruct
+	if err := json.Unmarshal(jsonBytes, &midtransRes); err != nil || midtransRes.StatusCode == "" {
+		var errRes MidtransErrorResponse
+		if err := json.Unmarshal(jsonBytes, &errRes); err == nil && len(errRes.ErrorMessages) > 0 {
+			return nil, fmt.Errorf("midtrans error: %s", strings.Join(errRes.ErrorMessages, "; "))
+		}
+		return nil, fmt.Errorf("midtrans returned HTTP %d but the response could not be parsed", HttpStatus)
+	}
+
+	switch midtransRes.StatusCode {
+	case SuccessCode:
+		return &midtransRes, nil
+	case "404":
+		return nil, ErrTransactionNotFound
+	}
+	return nil, fmt.Errorf("midtrans error: %s %s", midtransRes.StatusCode, midtransRes.StatusMessage)
+}
+
+func SendGetPaymentStatusToMidtrans(OrderID string, Vendor db_var.PaymentGatewayCredentialT) (*MidtransNotificationStruct, error) {
+	UrlEnvMode := global_var.PGUrlList.MidtransSend.Dev
+	if Vendor.Mode == "prod" {
+		UrlEnvMode = global_var.PGUrlList.MidtransSend.Prod
+	}
+
+	ApiKeys, err := helper.Decrypt(Vendor.APIKey, config.MasterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	Reqs := helper.RequestOptions{
+		Method:   "GET",
+		URL:      UrlEnvMode + "/v2/" + OrderID + "/status",
+		AuthType: helper.AuthBasic,
+		Username: ApiKeys,
+	}
+
+	Result, HttpStatus, _, err := helper.SendRequest(Reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	resMap, ok := Result.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected response format from Midtrans")
+	}
+
+	jsonBytes, err := json.Marshal(resMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-marshal result: %w", err)
+	}
+
+	if HttpStatus < 200 || HttpStatus >= 300 {
+		var errRes MidtransErrorResponse
+		if err := json.Unmarshal(jsonBytes, &errRes); err == nil && len(errRes.ErrorMessages) > 0 {
+			return nil, fmt.Errorf("midtrans error: %s", strings.Join(errRes.ErrorMessages, "; "))
+		}
+		return nil, fmt.Errorf("midtrans returned HTTP %d but error message could not be parsed", HttpStatus)
+	}
+
+	var midtransRes MidtransNotificationStruct
+	if err := json.Unmarshal(jsonBytes, &midtransRes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal to success struct: %w", err)
+	}
+
+	// Midtrans answers unknown orders and other errors with HTTP 200 and
+	// puts the real code in status_code
+	switch {
+	case midtransRes.StatusCode == "404":
+		return nil, ErrTransactionNotFound
+	case midtransRes.TransactionStatus == "":
+		return nil, fmt.Errorf("midtrans error: %s %s", midtransRes.StatusCode, midtransRes.StatusMessage)
+	}
+
+	return &midtransRes, nil
+}
+
+type midtransProvider struct{}
+
+func init() {
+	Register(global_var.PGVendor.Midtrans, midtransProvider{})
+}
+
+func (midtransProvider) Name() string {
+	return "midtrans"
+}
+
+// Midtrans order_id: up to 50 characters of letters, digits and - _ . ~
+var midtransOrderIDRules = OrderIDRules{
+	MaxLength: 50,
+	Pattern:   regexp.MustCompile(`^[A-Za-z0-9\-_.~]+$`),
+	Allowed:   "letters, digits and - _ . ~",
+}
+
+func (midtransProvider) OrderIDRules() OrderIDRules {
+	return midtransOrderIDRules
+}
+
+func (midtransProvider) CreatePayment(Req PaymentRequest, credential db_var.PaymentGatewayCredentialT) (*PaymentResult, error) {
+	RequestBody := MidtransTransactionRequest{
+		TransactionDetails: MidtransTransactionDetails{
+			OrderID:     Req.OrderID,
+			GrossAmount: Req.Amount,
+		},
+		CreditCard: MidtransCreditCard{Secure: true},
+	}
+
+	// Only add ItemDetails if exists
+	if Req.Items != nil && len(*Req.Items) > 0 {
+		var itemDetails []MidtransItemDetail
+		for i, v := range *Req.Items {
+			item := MidtransItemDetail{
+				ID:       fmt.Sprintf("%d", i+1),
+				Price:    v.Price,
+				Quantity: v.Quantity,
+				Name:     v.Name,
+			}
+			if v.Brand != "" {
+				item.Brand = &v.Brand
+			}
+			if v.Category != "" {
+				item.Category = &v.Category
+			}
+			itemDetails = append(itemDetails, item)
+		}
+		RequestBody.ItemDetails = &itemDetails
+	}
+
+	// Only add CustomerDetails if exists and valid
+	if Req.Customer != nil && Req.Customer.Email != "" {
+		customer := MidtransCustomerDetails{
+			FirstName: Req.Customer.FirstName,
+			LastName:  Req.Customer.LastName,
+			Email:     Req.Customer.Email,
+			Phone:     Req.Customer.Phone,
+		}
+		if Req.Customer.Billing != nil && Req.Customer.Billing.Email != "" {
+			customer.BillingAddress = MidtransAddress{
+				FirstName:   Req.Customer.Billing.FirstName,
+				LastName:    Req.Customer.Billing.LastName,
+				Email:       Req.Customer.Billing.Email,
+				Phone:       Req.Customer.Billing.Phone,
+				Address:     Req.Customer.Billing.AddressLine,
+				City:        Req.Customer.Billing.City,
+				PostalCode:  Req.Customer.Billing.PostalCode,
+				CountryCode: Req.Customer.Billing.CountryCode,
+			}
+			customer.ShippingAddress = customer.BillingAddress
+		}
+		RequestBody.CustomerDetails = &customer
+	}
+
+	// Only add Expiry if duration >= 20
+	if Req.Expiry != nil && Req.Expiry.Unit != "" {
+		RequestBody.Expiry = &MidtransExpiry{
+			Unit:     Req.Expiry.Unit,
+			Duration: Req.Expiry.Duration,
+		}
+	}
+
+	// Add Callbacks
+	cbUrl := FinishRedirectURL(Req, credential)
+	RequestBody.Callbacks = &MidtransCallbacks{Finish: &cbUrl}
+
+	// Add enabled payments
+	if Req.EnabledPayments != nil {
+		RequestBody.EnabledPayments = Req.EnabledPayments
+	} else {
+		// fallback to default list
+		RequestBody.EnabledPayments = &[]string{"credit_card", "mandiri_clickpay", "cimb_clicks", "bca_klikbca", "bca_klikpay", "bri_epay", "echannel", "mandiri_ecash", "permata_va", "bca_va", "bni_va", "other_va", "gopay", "indomaret", "alfamart", "danamon_online", "akulaku"}
+	}
+
+	RedirectUrl, err := SendRequestPaymentToMidtrans(RequestBody, credential)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaymentResult{RedirectURL: RedirectUrl}, nil
+}
+
+func (midtransProvider) GetStatus(transaction db_var.PaymentGatewayTransactionT, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error) {
+	res, err := SendGetPaymentStatusToMidtrans(transaction.OrderID, credential)
+	if err != nil {
+		return nil, err
+	}
+	return midtransTransactionStatus(*res), nil
+}
+
+func (midtransProvider) ParseNotification(req NotificationRequest, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error) {
+	var QueryParam MidtransNotificationStruct
+	if err := json.Unmarshal(req.Body, &QueryParam); err != nil {
+		return nil, err
+	}
+
+	if !req.Verified {
+		ServerKey, err := helper.Decrypt(credential.APIKey, config.MasterKey)
+		if err != nil || ServerKey == "" {
+			return nil, ErrInvalidSignature
+		}
+
+		if !VerifyMidtransSignature(QueryParam, ServerKey) {
+			return nil, ErrInvalidSignature
+		}
+	}
+
+	return midtransTransactionStatus(QueryParam), nil
+}
+
+// VerifyMidtransSignature checks signature_key, which Midtrans computes as
+// SHA512(order_id + status_code + gross_amount + server_key) in hex.
+func VerifyMidtransSignature(n MidtransNotificationStruct, serverKey string) bool {
+	if n.SignatureKey == "" {
+		return false
+	}
+	sum := sha512.Sum512([]byte(n.OrderID + n.StatusCode + n.GrossAmount + serverKey))
+	expected := hex.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(n.SignatureKey))) == 1
+}
+
+func (midtransProvider) Refund(req RefundRequest, credential db_var.PaymentGatewayCredentialT) (*RefundResult, error) {
+	res, err := SendRefundToMidtrans(req.OrderID, MidtransRefundRequest{
+		RefundKey: req.RefundID,
+		Amount:    req.Amount,
+		Reason:    req.Reason,
+	}, credential)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RefundResult{
+		VendorReference: res.RefundChargebackID.String(),
+		VendorStatus:    res.TransactionStatus,
+		Raw:             res,
+	}, nil
+}
+
+func (midtransProvider) Cancel(transaction db_var.PaymentGatewayTransactionT, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error) {
+	res, err := SendCancelToMidtrans(transaction.OrderID, credential)
+	if err != nil {
+		return nil, err
+	}
+	return midtransTransactionStatus(*res), nil
+}
+
+func (midtransProvider) Expire(transaction db_var.PaymentGatewayTransactionT, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error) {
+	res, err := SendExpireToMidtrans(transaction.OrderID, credential)
+	if err != nil {
+		return nil, err
+	}
+	return midtransTransactionStatus(*res), nil
+}
+
+func midtransTransactionStatus(n MidtransNotificationStruct) *TransactionStatus {
+	return &TransactionStatus{
+		OrderID:       n.OrderID,
+		TransactionID: n.TransactionID,
+		VendorStatus:  n.TransactionStatus,
+		Status:        MidtransCanonicalStatus(n.TransactionStatus, n.FraudStatus),
+		PaymentType:   n.PaymentType,
+		GrossAmount:   n.GrossAmount,
+		Raw:           n,
+	}
+}
+
+// MidtransCanonicalStatus maps a Midtrans transaction_status to a TxStatus* value.
+func MidtransCanonicalStatus(transactionStatus, fraudStatus string) string {
+	switch transactionStatus {
+	case "capture":
+		if fraudStatus == "challenge" {
+			return global_var.TxStatusWaitingPayment
+		}
+		if fraudStatus == "deny" {
+			return global_var.TxStatusFailed
+		}
+		return global_var.TxStatusPaid
+	case "settlement":
+		return global_var.TxStatusPaid
+	case "pending", "authorize":
+		return global_var.TxStatusWaitingPayment
+	case "deny", "failure":
+		return global_var.TxStatusFailed
+	case "cancel":
+		return global_var.TxStatusCancelled
+	case "expire":
+		return global_var.TxStatusExpired
+	case "refund":
+		return global_var.TxStatusRefunded
+	case "partial_refund":
+		return global_var.TxStatusPartiallyRefunded
+	}
+	return global_var.TxStatusPending
+}
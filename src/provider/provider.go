@@ -0,0 +1,300 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"pg_bridge_go/config"
+	"pg_bridge_go/db_var"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PaymentRequest is the vendor agnostic body accepted by create-payment-request.
+type PaymentRequest struct {
+	OrderID string `json:"order_id"`
+	// Amount is in minor units of the currency (see MinorUnitDigits): cents
+	// for SGD, whole rupiah for IDR. Stored amounts and vendor amount checks
+	// use the same unit.
+	Amount          int               `json:"amount"`
+	Items           *[]PaymentItem    `json:"items,omitempty"`
+	Customer        *CustomerInfo     `json:"customer,omitempty"`
+	EnabledPayments *[]string         `json:"enabled_payments,omitempty"`
+	Callbacks       *CallbackURLs     `json:"callbacks,omitempty"`
+	Expiry          *PaymentExpiry    `json:"expiry,omitempty"`
+	CustomFields    map[string]string `json:"custom_fields,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+	CheckoutMode    string            `json:"checkout_mode,omitempty"`
+	Currency        string            `json:"currency,omitempty"`
+}
+
+type PaymentItem struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Price    int    `json:"price"`
+	Quantity int    `json:"quantity"`
+	Brand    string `json:"brand,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+type CustomerInfo struct {
+	FirstName string   `json:"first_name"`
+	LastName  string   `json:"last_name"`
+	Email     string   `json:"email"`
+	Phone     string   `json:"phone"`
+	Billing   *Address `json:"billing,omitempty"`
+	Shipping  *Address `json:"shipping,omitempty"`
+}
+
+type Address struct {
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	Email       string `json:"email"`
+	Phone       string `json:"phone"`
+	AddressLine string `json:"address"`
+	City        string `json:"city"`
+	PostalCode  string `json:"postal_code"`
+	CountryCode string `json:"country_code"`
+}
+
+type CallbackURLs struct {
+	Finish string `json:"finish"`
+}
+
+type PaymentExpiry struct {
+	StartTime string `json:"start_time"`
+	Unit      string `json:"unit"` // e.g. "minutes"
+	Duration  int    `json:"duration"`
+}
+
+// Deadline returns the moment the payment expires when started at from.
+func (e PaymentExpiry) Deadline(from time.Time) (time.Time, bool) {
+	var unit time.Duration
+	switch strings.TrimSuffix(strings.ToLower(e.Unit), "s") {
+	case "second":
+		unit = time.Second
+	case "minute":
+		unit = time.Minute
+	case "hour":
+		unit = time.Hour
+	case "day":
+		unit = 24 * time.Hour
+	default:
+		return time.Time{}, false
+	}
+	if e.Duration <= 0 {
+		return time.Time{}, false
+	}
+	return from.Add(time.Duration(e.Duration) * unit), true
+}
+
+// PaymentResult is what a vendor hands back after a payment request is created.
+type PaymentResult struct {
+	RedirectURL     string
+	QRString        string
+	VendorReference string
+	VendorPayload   interface{}
+}
+
+// TransactionStatus is the vendor state of a single order, either pulled from
+// the status API or pushed through a notification.
+type TransactionStatus struct {
+	OrderID       string
+	TransactionID string
+	VendorStatus  string // raw status string as sent by the vendor
+	Status        string // canonical global_var.TxStatus* value
+	PaymentType   string
+	GrossAmount   string
+	Raw           interface{}
+}
+
+// NotificationRequest carries an inbound vendor notification as received.
+type NotificationRequest struct {
+	Path    string
+	Headers http.Header
+	Body    []byte
+	// Verified skips the signature checks of a notification that was
+	// verified when it arrived, such as a replay from the inbox whose secret
+	// headers were redacted.
+	Verified bool
+}
+
+// RedactedNotificationHeaders carry vendor secrets or signatures and are never
+// stored with a notification.
+var RedactedNotificationHeaders = []string{"X-Callback-Token", "Signature", "Client-Id", "Authorization"}
+
+// RedactNotificationHeaders returns a copy of headers with the values of
+// RedactedNotificationHeaders replaced.
+func RedactNotificationHeaders(headers http.Header) http.Header {
+	redacted := headers.Clone()
+	for _, name := range RedactedNotificationHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, "[redacted]")
+		}
+	}
+	return redacted
+}
+
+// RefundRequest describes a full or partial refund of a paid order.
+type RefundRequest struct {
+	OrderID  string
+	RefundID string
+	Amount   int
+	Reason   string
+}
+
+// RefundResult is the vendor answer to a refund request.
+type RefundResult struct {
+	VendorReference string
+	VendorStatus    string
+	Raw             interface{}
+}
+
+// MaxOrderIDLength is the size of the order_id column.
+const MaxOrderIDLength = 64
+
+// OrderIDRules are the limits a vendor puts on the merchant order id.
+type OrderIDRules struct {
+	MaxLength int
+	Pattern   *regexp.Regexp
+	// Allowed describes Pattern in error messages.
+	Allowed string
+}
+
+// Check reports whether orderID is acceptable for the vendor and fits the
+// order_id column.
+func (r OrderIDRules) Check(orderID string) error {
+	MaxLength := MaxOrderIDLength
+	if r.MaxLength > 0 && r.MaxLength < MaxLength {
+		MaxLength = r.MaxLength
+	}
+	if orderID == "" || len(orderID) > MaxLength {
+		return fmt.Errorf("order_id must be 1 to %d characters", MaxLength)
+	}
+	if r.Pattern != nil && !r.Pattern.MatchString(orderID) {
+		return fmt.Errorf("order_id may only contain %s", r.Allowed)
+	}
+	return nil
+}
+
+// PaymentProvider is implemented by every payment gateway adapter.
+type PaymentProvider interface {
+	// Name returns the lower case vendor name used in create-pg-vendor.
+	Name() string
+	OrderIDRules() OrderIDRules
+	CreatePayment(req PaymentRequest, credential db_var.PaymentGatewayCredentialT) (*PaymentResult, error)
+	GetStatus(transaction db_var.PaymentGatewayTransactionT, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error)
+	// ParseNotification parses and verifies an inbound notification.
+	ParseNotification(req NotificationRequest, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error)
+	Refund(req RefundRequest, credential db_var.PaymentGatewayCredentialT) (*RefundResult, error)
+	// Cancel and Expire close an unpaid order at the vendor so it can no
+	// longer be paid.
+	Cancel(transaction db_var.PaymentGatewayTransactionT, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error)
+	Expire(transaction db_var.PaymentGatewayTransactionT, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error)
+}
+
+var (
+	ErrNotSupported     = errors.New("operation not supported by this vendor")
+	ErrInvalidSignature = errors.New("invalid notification signature")
+	// ErrTransactionNotFound means the vendor has no transaction for the order,
+	// for example when the customer never opened the payment page.
+	ErrTransactionNotFound = errors.New("transaction not found at the vendor")
+	// ErrVendorRejected means the vendor answered and refused the request, so
+	// it definitely did not take effect. Other errors leave the outcome unknown.
+	ErrVendorRejected = errors.New("rejected by the vendor")
+)
+
+var (
+	registry      = map[string]PaymentProvider{}
+	registryMutex sync.RWMutex
+)
+
+// Register adds a provider under its vendor prefix (see global_var.PGVendor).
+func Register(prefix string, p PaymentProvider) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registry[prefix] = p
+}
+
+// Get returns the provider registered for a vendor prefix.
+func Get(prefix string) (PaymentProvider, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	p, ok := registry[prefix]
+	return p, ok
+}
+
+// ForVendorCode resolves the provider from a credential code such as "MIDTR-12".
+func ForVendorCode(vendorCode string) (PaymentProvider, bool) {
+	return Get(VendorPrefix(vendorCode))
+}
+
+// VendorPrefix returns the vendor prefix part of a credential code.
+func VendorPrefix(vendorCode string) string {
+	return strings.SplitN(vendorCode, "-", 2)[0]
+}
+
+// FinishRedirectURL returns where the customer is sent back to after paying.
+func FinishRedirectURL(req PaymentRequest, credential db_var.PaymentGatewayCredentialT) string {
+	if credential.CallbackRedirect == 1 && req.Callbacks != nil && req.Callbacks.Finish != "" {
+		return req.Callbacks.Finish
+	}
+	return config.CallbackUrl + "/callback/" + credential.Code + "/payment"
+}
+
+// NotificationURL returns the bridge endpoint vendors should post notifications to.
+func NotificationURL(credential db_var.PaymentGatewayCredentialT) string {
+	return config.CallbackUrl + "/callback/" + credential.Code + "/notification"
+}
+
+// zeroDecimalCurrencies are charged in whole units, so their minor unit is
+// the currency unit itself. Rupiah has cents on paper but no vendor takes them.
+var zeroDecimalCurrencies = []string{"IDR", "JPY", "KRW", "VND"}
+
+// MinorUnitDigits returns how many decimals an amount in currency has.
+func MinorUnitDigits(currency string) int {
+	if slices.Contains(zeroDecimalCurrencies, strings.ToUpper(currency)) {
+		return 0
+	}
+	return 2
+}
+
+// MajorAmount converts an amount in minor units into the currency unit, e.g.
+// 1050 SGD into 10.5.
+func MajorAmount(amount int, currency string) float64 {
+	return float64(amount) / math.Pow10(MinorUnitDigits(currency))
+}
+
+// MinorAmount converts an amount in the currency unit into minor units.
+func MinorAmount(amount float64, currency string) int {
+	return int(math.Round(amount * math.Pow10(MinorUnitDigits(currency))))
+}
+
+// FormatMinorAmount renders an amount in minor units as the decimal string
+// vendors expect, e.g. 1050 SGD as "10.50".
+func FormatMinorAmount(amount int, currency string) string {
+	return strconv.FormatFloat(MajorAmount(amount, currency), 'f', MinorUnitDigits(currency), 64)
+}
+
+// ParseMinorAmount converts a vendor decimal amount such as "10.50" SGD into
+// minor units.
+func ParseMinorAmount(value, currency string) (int, error) {
+	amount, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, err
+	}
+	return MinorAmount(amount, currency), nil
+}
+
+// CurrencyOrDefault returns the requested currency or the vendor default.
+func CurrencyOrDefault(req PaymentRequest, fallback string) string {
+	if req.Currency != "" {
+		return strings.ToUpper(req.Currency)
+	}
+	return fallback
+}
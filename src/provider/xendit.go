@@ -0,0 +1,385 @@
+package provider
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"pg_bridge_go/config"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/helper"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const XenditQRApiVersion = "2022-07-31"
+
+type xenditProvider struct{}
+
+func init() {
+	Register(global_var.PGVendor.Xendit, xenditProvider{})
+}
+
+func (xenditProvider) Name() string {
+	return "xendit"
+}
+
+// Xendit reference_id / external_id accept up to 255 characters
+var xenditOrderIDRules = OrderIDRules{
+	MaxLength: 255,
+	Pattern:   regexp.MustCompile(`^[A-Za-z0-9\-_.~]+$`),
+	Allowed:   "letters, digits and - _ . ~",
+}
+
+func (xenditProvider) OrderIDRules() OrderIDRules {
+	return xenditOrderIDRules
+}
+
+// SendXenditRequest calls the Xendit API with the credential secret key and
+// decodes a successful response into out.
+func SendXenditRequest(Reqs helper.RequestOptions, Vendor db_var.PaymentGatewayCredentialT, out interface{}) error {
+	UrlEnvMode := global_var.PGUrlList.Xendit.Dev
+	if Vendor.Mode == "prod" {
+		UrlEnvMode = global_var.PGUrlList.Xendit.Prod
+	}
+
+	ApiKeys, err := helper.Decrypt(Vendor.APIKey, config.MasterKey)
+	if err != nil {
+		return err
+	}
+
+	Reqs.URL = UrlEnvMode + Reqs.URL
+	Reqs.AuthType = helper.AuthBasic
+	Reqs.Username = ApiKeys
+	Reqs.ContentType = "application/json"
+
+	Result, HttpStatus, _, err := helper.SendRequest(Reqs)
+	if err != nil {
+		return err
+	}
+
+	resMap, ok := Result.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected response format from Xendit")
+	}
+
+	jsonBytes, err := json.Marshal(resMap)
+	if err != nil {
+		return fmt.Errorf("failed to re-marshal result: %w", err)
+	}
+
+	if HttpStatus < 200 || HttpStatus >= 300 {
+		var errRes global_var.XDNT_ErrorBody
+		if err := json.Unmarshal(jsonBytes, &errRes); err == nil && errRes.Message != "" {
+			return fmt.Errorf("xendit error: %s (%s)", errRes.Message, errRes.ErrorCode)
+		}
+		return fmt.Errorf("xendit returned HTTP %d but error message could not be parsed", HttpStatus)
+	}
+
+	if err := json.Unmarshal(jsonBytes, out); err != nil {
+		return fmt.Errorf("failed to unmarshal to success struct: %w", err)
+	}
+	return nil
+}
+
+// xenditCheckoutMode picks QR or invoice, the request overriding the credential.
+func xenditCheckoutMode(Req PaymentRequest, credential db_var.PaymentGatewayCredentialT) string {
+	if Req.CheckoutMode != "" {
+		return Req.CheckoutMode
+	}
+	if credential.CheckoutMode != "" {
+		return credential.CheckoutMode
+	}
+	return global_var.CheckoutModeQR
+}
+
+func (p xenditProvider) CreatePayment(Req PaymentRequest, credential db_var.PaymentGatewayCredentialT) (*PaymentResult, error) {
+	CheckoutMode := xenditCheckoutMode(Req, credential)
+	switch CheckoutMode {
+	case global_var.CheckoutModeQR:
+		return p.createQRCode(Req, credential)
+	case global_var.CheckoutModeInvoice:
+		return p.createInvoice(Req, credential)
+	}
+	return nil, fmt.Errorf("unsupported xendit checkout mode %q", CheckoutMode)
+}
+
+func (xenditProvider) createQRCode(Req PaymentRequest, credential db_var.PaymentGatewayCredentialT) (*PaymentResult, error) {
+	Currency := CurrencyOrDefault(Req, "IDR")
+	RequestBody := global_var.XDNT_RequestBody{
+		ReferenceID: Req.OrderID,
+		Type:        "DYNAMIC",
+		Currency:    Currency,
+		Amount:      MajorAmount(Req.Amount, Currency),
+	}
+
+	if Req.Items != nil {
+		for i, v := range *Req.Items {
+			ReferenceID := v.ID
+			if ReferenceID == "" {
+				ReferenceID = fmt.Sprintf("%d", i+1)
+			}
+			RequestBody.Basket = append(RequestBody.Basket, global_var.XDNT_ItemDetail_RequestBody{
+				ReferenceID: ReferenceID,
+				Name:        v.Name,
+				Currency:    Currency,
+				Price:       MajorAmount(v.Price, Currency),
+				Quantity:    v.Quantity,
+				Description: v.Category,
+			})
+		}
+	}
+
+	if Req.Expiry != nil {
+		if ExpiresAt, ok := Req.Expiry.Deadline(time.Now()); ok {
+			RequestBody.ExpiresAt = &ExpiresAt
+		}
+	}
+
+	Reqs := helper.RequestOptions{
+		Method:  "POST",
+		URL:     "/qr_codes",
+		Body:    RequestBody,
+		Headers: map[string]string{"api-version": XenditQRApiVersion},
+	}
+
+	var Result global_var.XDNT_ResultBody
+	if err := SendXenditRequest(Reqs, credential, &Result); err != nil {
+		return nil, err
+	}
+
+	return &PaymentResult{
+		QRString:        Result.QRString,
+		VendorReference: Result.ID,
+		VendorPayload:   Result,
+	}, nil
+}
+
+func (xenditProvider) createInvoice(Req PaymentRequest, credential db_var.PaymentGatewayCredentialT) (*PaymentResult, error) {
+	Currency := CurrencyOrDefault(Req, "IDR")
+	RequestBody := global_var.XDNT_InvoiceRequestBody{
+		ExternalID: Req.OrderID,
+		Amount:     MajorAmount(Req.Amount, Currency),
+		Currency:   Currency,
+	}
+
+	if Req.Items != nil {
+		for _, v := range *Req.Items {
+			RequestBody.Items = append(RequestBody.Items, global_var.XDNT_InvoiceItem{
+				Name:     v.Name,
+				Quantity: v.Quantity,
+				Price:    MajorAmount(v.Price, Currency),
+				Category: v.Category,
+			})
+		}
+	}
+
+	if Req.Customer != nil {
+		RequestBody.Customer = &global_var.XDNT_InvoiceCustomer{
+			GivenNames:   Req.Customer.FirstName,
+			Surname:      Req.Customer.LastName,
+			Email:        Req.Customer.Email,
+			MobileNumber: Req.Customer.Phone,
+		}
+	}
+
+	if Req.Expiry != nil {
+		now := time.Now()
+		if ExpiresAt, ok := Req.Expiry.Deadline(now); ok {
+			RequestBody.InvoiceDuration = int(ExpiresAt.Sub(now).Seconds())
+		}
+	}
+
+	if Req.EnabledPayments != nil {
+		RequestBody.PaymentMethods = *Req.EnabledPayments
+	}
+
+	// Xendit does not append anything to the redirect, so the order has to be in it
+	RedirectURL, err := url.Parse(FinishRedirectURL(Req, credential))
+	if err != nil {
+		return nil, err
+	}
+	Query := RedirectURL.Query()
+	Query.Set("order_id", Req.OrderID)
+	RedirectURL.RawQuery = Query.Encode()
+	RequestBody.SuccessRedirectURL = RedirectURL.String()
+	RequestBody.FailureRedirectURL = RedirectURL.String()
+
+	Reqs := helper.RequestOptions{
+		Method: "POST",
+		URL:    "/v2/invoices",
+		Body:   RequestBody,
+	}
+
+	var Result global_var.XDNT_InvoiceBody
+	if err := SendXenditRequest(Reqs, credential, &Result); err != nil {
+		return nil, err
+	}
+
+	return &PaymentResult{
+		RedirectURL:     Result.InvoiceURL,
+		VendorReference: Result.ID,
+		VendorPayload:   Result,
+	}, nil
+}
+
+func (xenditProvider) GetStatus(transaction db_var.PaymentGatewayTransactionT, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error) {
+	if transaction.VendorReference == "" {
+		return nil, fmt.Errorf("transaction %s has no xendit reference", transaction.OrderID)
+	}
+
+	// QR code ids are prefixed with "qr_", anything else is an invoice id
+	if !strings.HasPrefix(transaction.VendorReference, "qr_") {
+		Reqs := helper.RequestOptions{
+			Method: "GET",
+			URL:    "/v2/invoices/" + transaction.VendorReference,
+		}
+
+		var Result global_var.XDNT_InvoiceBody
+		if err := SendXenditRequest(Reqs, credential, &Result); err != nil {
+			return nil, err
+		}
+		return xenditInvoiceTransactionStatus(Result), nil
+	}
+
+	Reqs := helper.RequestOptions{
+		Method:  "GET",
+		URL:     "/qr_codes/" + transaction.VendorReference + "/payments",
+		Headers: map[string]string{"api-version": XenditQRApiVersion},
+	}
+
+	var Result global_var.XDNT_QRPaymentListBody
+	if err := SendXenditRequest(Reqs, credential, &Result); err != nil {
+		return nil, err
+	}
+
+	for _, v := range Result.Data {
+		if v.Status == "SUCCEEDED" {
+			return xenditQRTransactionStatus(v), nil
+		}
+	}
+
+	return &TransactionStatus{
+		OrderID:      transaction.OrderID,
+		VendorStatus: "ACTIVE",
+		Status:       global_var.TxStatusWaitingPayment,
+		Raw:          Result,
+	}, nil
+}
+
+func (xenditProvider) ParseNotification(req NotificationRequest, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error) {
+	if !req.Verified {
+		if err := verifyXenditCallbackToken(req, credential); err != nil {
+			return nil, err
+		}
+	}
+
+	var Callback global_var.XDNT_QRCallbackBody
+	if err := json.Unmarshal(req.Body, &Callback); err != nil {
+		return nil, err
+	}
+
+	// Invoice callbacks carry the invoice itself instead of an event envelope
+	if Callback.Event == "" {
+		var Invoice global_var.XDNT_InvoiceBody
+		if err := json.Unmarshal(req.Body, &Invoice); err != nil {
+			return nil, err
+		}
+		return xenditInvoiceTransactionStatus(Invoice), nil
+	}
+
+	if Callback.Event != "qr.payment" {
+		return &TransactionStatus{
+			OrderID:      Callback.Data.ReferenceID,
+			VendorStatus: Callback.Event,
+			Status:       global_var.TxStatusPending,
+			Raw:          Callback,
+		}, nil
+	}
+
+	return xenditQRTransactionStatus(Callback.Data), nil
+}
+
+func (xenditProvider) Refund(req RefundRequest, credential db_var.PaymentGatewayCredentialT) (*RefundResult, error) {
+	return nil, ErrNotSupported
+}
+
+func (xenditProvider) Cancel(transaction db_var.PaymentGatewayTransactionT, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error) {
+	return nil, ErrNotSupported
+}
+
+func (xenditProvider) Expire(transaction db_var.PaymentGatewayTransactionT, credential db_var.PaymentGatewayCredentialT) (*TransactionStatus, error) {
+	return nil, ErrNotSupported
+}
+
+// verifyXenditCallbackToken compares the x-callback-token header with the
+// verification token stored encrypted in the credential APISecret.
+func verifyXenditCallbackToken(req NotificationRequest, credential db_var.PaymentGatewayCredentialT) error {
+	Token, err := helper.Decrypt(credential.APISecret, config.MasterKey)
+	if err != nil || Token == "" {
+		return ErrInvalidSignature
+	}
+
+	Received := req.Headers.Get("x-callback-token")
+	if subtle.ConstantTimeCompare([]byte(Received), []byte(Token)) != 1 {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func xenditQRTransactionStatus(p global_var.XDNT_QRPaymentBody) *TransactionStatus {
+	Status := global_var.TxStatusWaitingPayment
+	switch p.Status {
+	case "SUCCEEDED":
+		Status = global_var.TxStatusPaid
+	case "FAILED":
+		Status = global_var.TxStatusFailed
+	}
+
+	return &TransactionStatus{
+		OrderID:       p.ReferenceID,
+		TransactionID: p.ID,
+		VendorStatus:  p.Status,
+		Status:        Status,
+		PaymentType:   "qris",
+		GrossAmount:   xenditMinorAmount(p.Amount, p.Currency),
+		Raw:           p,
+	}
+}
+
+func xenditInvoiceTransactionStatus(i global_var.XDNT_InvoiceBody) *TransactionStatus {
+	Status := global_var.TxStatusWaitingPayment
+	switch i.Status {
+	case "PAID", "SETTLED":
+		Status = global_var.TxStatusPaid
+	case "EXPIRED":
+		Status = global_var.TxStatusExpired
+	}
+
+	Amount := i.PaidAmount
+	if Amount == 0 {
+		Amount = i.Amount
+	}
+
+	return &TransactionStatus{
+		OrderID:       i.ExternalID,
+		TransactionID: i.ID,
+		VendorStatus:  i.Status,
+		Status:        Status,
+		PaymentType:   strings.ToLower(i.PaymentMethod),
+		GrossAmount:   xenditMinorAmount(Amount, i.Currency),
+		Raw:           i,
+	}
+}
+
+// xenditMinorAmount converts a Xendit amount into the minor units the bridge
+// stores. Payloads without a currency are rupiah.
+func xenditMinorAmount(amount float64, currency string) string {
+	if currency == "" {
+		currency = "IDR"
+	}
+	return strconv.Itoa(MinorAmount(amount, currency))
+}
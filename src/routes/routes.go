@@ -2,6 +2,7 @@ package routes
 
 import (
 	"pg_bridge_go/controllers"
+	"pg_bridge_go/global_var"
 	"pg_bridge_go/middleware"
 
 	"github.com/gofiber/fiber/v2"
@@ -24,22 +25,65 @@ func SetupRouter() *fiber.App {
 	admin := v1.Group("/admin", middleware.BasicAuthMiddlewareAdmin())
 	admin.Get("/ping", controllers.Ping)
 	admin.Post("/register", controllers.RegisterHandler)
+	admin.Put("/users/:username/role", controllers.UpdateUserRole)
+	admin.Post("/organizations", controllers.CreateOrganization)
+	admin.Post("/organizations/:code/members", controllers.MoveOrganizationMember)
+	admin.Post("/reconciliation-runs", controllers.StartReconciliationRun)
+	admin.Get("/reconciliation-runs", controllers.GetAllReconciliationRun)
+	admin.Get("/reconciliation-runs/:id", controllers.GetReconciliationRun)
+	admin.Get("/notifications", controllers.GetAllNotificationInbox)
+	admin.Post("/notifications/replay", controllers.ReplayNotifications)
+	admin.Get("/notifications/:id", controllers.GetNotificationInbox)
+	admin.Post("/notifications/:id/replay", controllers.ReplayNotificationInbox)
 
 	cb := v1.Group("/callback/:vendorcode")
 	cb.Get("/payment", controllers.PaymentCallback)
 	cb.Post("/notification", controllers.HandlePostNotificationFromPG)
 
-	pg := v1.Group("/pg", middleware.BasicAuthMiddleware())
+	readCredentials := middleware.RequireScope(global_var.ScopeCredentialsRead, global_var.ScopeCredentialsReadSecrets, global_var.ScopeCredentialsManage)
+	manageCredentials := middleware.RequireScope(global_var.ScopeCredentialsManage)
+	manageAPIKeys := middleware.RequireScope(global_var.ScopeAPIKeysManage)
+	manageMembers := middleware.RequireScope(global_var.ScopeMembersManage)
+	createPayments := middleware.RequireScope(global_var.ScopePaymentsCreate)
+	readPayments := middleware.RequireScope(global_var.ScopePaymentsRead)
+	readWebhooks := middleware.RequireScope(global_var.ScopePaymentsRead, global_var.ScopeWebhooksManage)
+	manageWebhooks := middleware.RequireScope(global_var.ScopeWebhooksManage)
+	createRefunds := middleware.RequireScope(global_var.ScopeRefundsCreate)
+
+	pg := v1.Group("/pg", middleware.MerchantAuthMiddleware())
 	pg.Get("/ping", controllers.Ping)
-	pg.Post("/create-pg-vendor", controllers.CreatePaymentGatewayCredential)
-	pg.Get("/get-pg-vendor/:code", controllers.GetPaymentGatewayCredential)
-	pg.Get("/get-all-pg-vendor", controllers.GetAllPaymentGatewayCredential)
-	pg.Put("/update-pg-vendor/:code", controllers.UpdatePaymentGatewayCredential)
-	pg.Delete("/delete-pg-vendor/:code", controllers.DeletePaymentGatewayCredential)
+	pg.Post("/create-pg-vendor", manageCredentials, controllers.CreatePaymentGatewayCredential)
+	pg.Get("/get-pg-vendor/:code", readCredentials, controllers.GetPaymentGatewayCredential)
+	pg.Get("/get-all-pg-vendor", readCredentials, controllers.GetAllPaymentGatewayCredential)
+	pg.Put("/update-pg-vendor/:code", manageCredentials, controllers.UpdatePaymentGatewayCredential)
+	pg.Delete("/delete-pg-vendor/:code", manageCredentials, controllers.DeletePaymentGatewayCredential)
+	pg.Post("/rotate-webhook-secret/:code", manageCredentials, controllers.RotateWebhookSecret)
+
+	pg.Post("/api-keys", manageAPIKeys, controllers.CreateAPIKey)
+	pg.Get("/api-keys", manageAPIKeys, controllers.GetAllAPIKey)
+	pg.Delete("/api-keys/:prefix", manageAPIKeys, controllers.RevokeAPIKey)
+
+	pg.Get("/organization", controllers.GetOrganization)
+	pg.Post("/organization/members", manageMembers, controllers.CreateOrganizationMember)
+	pg.Delete("/organization/members/:username", manageMembers, controllers.RemoveOrganizationMember)
+
+	pg.Get("/webhook-deliveries", readWebhooks, controllers.GetAllWebhookDelivery)
+	pg.Get("/webhook-deliveries/:id", readWebhooks, controllers.GetWebhookDelivery)
+	pg.Post("/webhook-deliveries/:id/redeliver", manageWebhooks, controllers.RedeliverWebhook)
+
+	pg.Get("/transactions/:order_id", readPayments, controllers.GetTransactionDetail)
+	pg.Get("/reconciliation-items", readPayments, controllers.GetAllReconciliationItem)
+	pg.Get("/settlement-imports", readPayments, controllers.GetAllSettlementImport)
+	pg.Get("/settlement-imports/:id", readPayments, controllers.GetSettlementImport)
 
 	pgVendor := pg.Group("/vendor/:vendorcode")
-	pgVendor.Post("/create-payment-request", controllers.HandleCreatePayment)
-	pgVendor.Get("/get-payment-status", controllers.HandleGetPaymentStatus)
+	pgVendor.Post("/create-payment-request", createPayments, middleware.IdempotencyMiddleware(), controllers.HandleCreatePayment)
+	pgVendor.Get("/get-payment-status", readPayments, controllers.HandleGetPaymentStatus)
+	pgVendor.Post("/settlement-import", readPayments, controllers.ImportSettlementFile)
+	pgVendor.Post("/cancel", createPayments, controllers.HandleCancelPayment)
+	pgVendor.Post("/expire", createPayments, controllers.HandleExpirePayment)
+	pgVendor.Post("/refund", createRefunds, controllers.HandleRefund)
+	pgVendor.Get("/refunds", readPayments, controllers.GetAllRefund)
 
 	return app
 }
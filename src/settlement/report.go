@@ -0,0 +1,239 @@
+package settlement
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/provider"
+	"sort"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	ResultMatched             = "matched"
+	ResultAmountMismatch      = "amount_mismatch"
+	ResultMissingInBridge     = "missing_in_bridge"
+	ResultMissingInSettlement = "missing_in_settlement"
+)
+
+// Item is one line of the report. Settlement fields are empty for
+// missing_in_settlement and bridge fields for missing_in_bridge.
+type Item struct {
+	Result           string  `json:"result"`
+	OrderID          string  `json:"order_id"`
+	Line             int     `json:"line,omitempty"`
+	SettlementAmount float64 `json:"settlement_amount"`
+	Fee              float64 `json:"fee"`
+	Net              float64 `json:"net"`
+	SettlementStatus string  `json:"settlement_status,omitempty"`
+	PaymentType      string  `json:"payment_type,omitempty"`
+	BridgeAmount     int     `json:"bridge_amount"`
+	BridgeStatus     string  `json:"bridge_status,omitempty"`
+}
+
+// lookupBatchSize is how many order ids one transaction lookup binds.
+const lookupBatchSize = 5000
+
+// unsettledStatuses are bridge statuses that should not show up in a
+// settlement file, so their absence is not a discrepancy.
+var unsettledStatuses = []string{
+	global_var.TxStatusPending,
+	global_var.TxStatusWaitingPayment,
+	global_var.TxStatusExpired,
+	global_var.TxStatusFailed,
+	global_var.TxStatusCancelled,
+}
+
+// Import parses a settlement file for a credential, matches it against the
+// credential transactions and stores the report. Without start and end the
+// period is taken from the row dates; missing_in_settlement is only reported
+// when a period is known.
+func Import(db *gorm.DB, credential db_var.PaymentGatewayCredentialT, fileName string, file io.Reader, start, end *time.Time, createdBy string) (*db_var.SettlementImportT, error) {
+	rows, ignored, err := Parse(provider.VendorPrefix(credential.Code), file)
+	if err != nil {
+		return nil, err
+	}
+
+	if start == nil || end == nil {
+		start, end = rowPeriod(rows)
+	}
+
+	OrderIDs := make([]string, 0, len(rows))
+	for _, row := range rows {
+		OrderIDs = append(OrderIDs, row.OrderID)
+	}
+
+	// One bind parameter per order id, so large files are looked up in
+	// batches to stay under the Postgres parameter limit
+	var known []db_var.PaymentGatewayTransactionT
+	for len(OrderIDs) > 0 {
+		batch := OrderIDs[:min(len(OrderIDs), lookupBatchSize)]
+		OrderIDs = OrderIDs[len(batch):]
+
+		var found []db_var.PaymentGatewayTransactionT
+		if err := db.Where("vendor = ? AND order_id IN ?", credential.Code, batch).Find(&found).Error; err != nil {
+			return nil, err
+		}
+		known = append(known, found...)
+	}
+
+	var expected []db_var.PaymentGatewayTransactionT
+	if start != nil && end != nil {
+		err := db.Where("vendor = ? AND status NOT IN ? AND created_at BETWEEN ? AND ?", credential.Code, unsettledStatuses, *start, *end).
+			Find(&expected).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report := Build(rows, known, expected)
+	items, err := json.Marshal(report.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	record := db_var.SettlementImportT{
+		OrganizationCode:    credential.OrganizationCode,
+		VendorCode:          credential.Code,
+		FileName:            fileName,
+		PeriodStart:         start,
+		PeriodEnd:           end,
+		Rows:                len(rows),
+		Ignored:             ignored,
+		Matched:             report.Matched,
+		AmountMismatched:    report.AmountMismatched,
+		MissingInBridge:     report.MissingInBridge,
+		MissingInSettlement: report.MissingInSettlement,
+		TotalGross:          report.TotalGross,
+		TotalFee:            report.TotalFee,
+		TotalNet:            report.TotalNet,
+		Items:               datatypes.JSON(items),
+		CreatedBy:           createdBy,
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Report is the outcome of matching settlement rows with bridge transactions.
+type Report struct {
+	Matched             int
+	AmountMismatched    int
+	MissingInBridge     int
+	MissingInSettlement int
+	TotalGross          float64
+	TotalFee            float64
+	TotalNet            float64
+	Items               []Item
+}
+
+// Build matches rows against the known transactions (looked up by the row
+// order ids) and the expected ones (every settled transaction of the period).
+func Build(rows []Row, known, expected []db_var.PaymentGatewayTransactionT) Report {
+	byOrderID := map[string]db_var.PaymentGatewayTransactionT{}
+	for _, transaction := range known {
+		byOrderID[transaction.OrderID] = transaction
+	}
+
+	var report Report
+	seen := map[string]bool{}
+	for _, row := range rows {
+		seen[row.OrderID] = true
+		report.TotalGross += row.Amount
+		report.TotalFee += row.Fee
+		report.TotalNet += row.Net
+
+		item := Item{
+			OrderID:          row.OrderID,
+			Line:             row.Line,
+			SettlementAmount: row.Amount,
+			Fee:              row.Fee,
+			Net:              row.Net,
+			SettlementStatus: row.Status,
+			PaymentType:      row.PaymentType,
+		}
+
+		transaction, ok := byOrderID[row.OrderID]
+		switch {
+		case !ok:
+			item.Result = ResultMissingInBridge
+			report.MissingInBridge++
+		case math.Abs(row.Amount-float64(transaction.Amount)) > 0.001:
+			item.Result = ResultAmountMismatch
+			report.AmountMismatched++
+		default:
+			item.Result = ResultMatched
+			report.Matched++
+		}
+		if ok {
+			item.BridgeAmount = transaction.Amount
+			item.BridgeStatus = transaction.Status
+		}
+		report.Items = append(report.Items, item)
+	}
+
+	for _, transaction := range expected {
+		if seen[transaction.OrderID] {
+			continue
+		}
+		report.MissingInSettlement++
+		report.Items = append(report.Items, Item{
+			Result:       ResultMissingInSettlement,
+			OrderID:      transaction.OrderID,
+			BridgeAmount: transaction.Amount,
+			BridgeStatus: transaction.Status,
+		})
+	}
+
+	// Discrepancies first, then by order id
+	sort.SliceStable(report.Items, func(i, j int) bool {
+		a, b := report.Items[i], report.Items[j]
+		if (a.Result == ResultMatched) != (b.Result == ResultMatched) {
+			return b.Result == ResultMatched
+		}
+		return a.OrderID < b.OrderID
+	})
+	return report
+}
+
+// ParsePeriod parses optional YYYY-MM-DD bounds, the end date inclusive. Both
+// empty means the period comes from the file.
+func ParsePeriod(StartDate, EndDate string) (*time.Time, *time.Time, error) {
+	if StartDate == "" && EndDate == "" {
+		return nil, nil, nil
+	}
+	start, err := time.ParseInLocation("2006-01-02", StartDate, time.Local)
+	if err != nil {
+		return nil, nil, errors.New("start date and end date must both be YYYY-MM-DD")
+	}
+	end, err := time.ParseInLocation("2006-01-02", EndDate, time.Local)
+	if err != nil {
+		return nil, nil, errors.New("start date and end date must both be YYYY-MM-DD")
+	}
+	end = end.Add(24*time.Hour - time.Nanosecond)
+	return &start, &end, nil
+}
+
+// rowPeriod returns the first and last row date, or nil when rows carry none.
+func rowPeriod(rows []Row) (*time.Time, *time.Time) {
+	var start, end *time.Time
+	for _, row := range rows {
+		if row.Date == nil {
+			continue
+		}
+		if start == nil || row.Date.Before(*start) {
+			start = row.Date
+		}
+		if end == nil || row.Date.After(*end) {
+			end = row.Date
+		}
+	}
+	return start, end
+}
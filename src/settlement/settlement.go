@@ -0,0 +1,211 @@
+// Package settlement parses vendor settlement and transaction report files
+// and matches them against the bridge transactions.
+package settlement
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"pg_bridge_go/global_var"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColumnMapping names the report columns of one vendor. Each field lists the
+// header names the vendor has used, matched case insensitively.
+type ColumnMapping struct {
+	OrderID     []string
+	Amount      []string
+	Fee         []string
+	Net         []string
+	Status      []string
+	PaymentType []string
+	// Date should be the transaction time, it bounds the report period
+	Date []string
+	// SettledStatuses are the row statuses that count as money received.
+	// Rows with another status are ignored. Empty means every row counts.
+	SettledStatuses []string
+	DateLayouts     []string
+}
+
+// Mappings holds the column mapping per vendor prefix.
+var Mappings = map[string]ColumnMapping{
+	global_var.PGVendor.Midtrans: {
+		OrderID:         []string{"Order ID"},
+		Amount:          []string{"Gross Amount", "Amount"},
+		Fee:             []string{"Fee", "Total Fee", "MDR"},
+		Net:             []string{"Net Amount", "Nett Amount", "Settlement Amount"},
+		Status:          []string{"Transaction Status", "Status"},
+		PaymentType:     []string{"Payment Type", "Payment Method"},
+		Date:            []string{"Transaction Time", "Settlement Time", "Settlement Date"},
+		SettledStatuses: []string{"settlement", "capture"},
+		DateLayouts:     []string{"2006-01-02 15:04:05", "2006-01-02", "02/01/2006 15:04", "02/01/2006"},
+	},
+	global_var.PGVendor.Xendit: {
+		OrderID:         []string{"Reference", "Reference ID", "External ID"},
+		Amount:          []string{"Amount", "Transaction Amount"},
+		Fee:             []string{"Fee", "Xendit Fee", "Total Fee"},
+		Net:             []string{"Net Amount", "Settlement Amount"},
+		Status:          []string{"Status"},
+		PaymentType:     []string{"Channel", "Payment Method", "Channel Code"},
+		Date:            []string{"Transaction Date", "Created", "Date", "Settlement Date"},
+		SettledStatuses: []string{"SETTLED", "PAID", "SUCCEEDED", "COMPLETED"},
+		DateLayouts:     []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"},
+	},
+}
+
+// Row is one money movement from a settlement file.
+type Row struct {
+	Line        int
+	OrderID     string
+	Amount      float64
+	Fee         float64
+	Net         float64
+	Status      string
+	PaymentType string
+	Date        *time.Time
+}
+
+// Parse reads a CSV report for the vendor. It returns the settled rows and
+// how many rows were ignored because of their status.
+func Parse(vendorPrefix string, r io.Reader) ([]Row, int, error) {
+	mapping, ok := Mappings[vendorPrefix]
+	if !ok {
+		return nil, 0, fmt.Errorf("settlement files are not supported for vendor %s", vendorPrefix)
+	}
+
+	reader := newCSVReader(r)
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, 0, errors.New("settlement file is empty")
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	find := func(names []string) int {
+		for _, name := range names {
+			if i, ok := columns[strings.ToLower(name)]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+
+	orderCol, amountCol := find(mapping.OrderID), find(mapping.Amount)
+	if orderCol < 0 || amountCol < 0 {
+		return nil, 0, fmt.Errorf("settlement file needs an order id column (%s) and an amount column (%s)",
+			strings.Join(mapping.OrderID, ", "), strings.Join(mapping.Amount, ", "))
+	}
+	feeCol, netCol := find(mapping.Fee), find(mapping.Net)
+	statusCol, typeCol, dateCol := find(mapping.Status), find(mapping.PaymentType), find(mapping.Date)
+
+	var rows []Row
+	ignored := 0
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, 0, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		cell := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row := Row{
+			Line:        line,
+			OrderID:     cell(orderCol),
+			Status:      cell(statusCol),
+			PaymentType: cell(typeCol),
+		}
+		if row.OrderID == "" {
+			continue
+		}
+
+		if statusCol >= 0 && len(mapping.SettledStatuses) > 0 && !containsFold(mapping.SettledStatuses, row.Status) {
+			ignored++
+			continue
+		}
+
+		if row.Amount, err = parseAmount(cell(amountCol)); err != nil {
+			return nil, 0, fmt.Errorf("line %d: amount: %w", line, err)
+		}
+		if row.Fee, err = parseAmount(cell(feeCol)); err != nil {
+			return nil, 0, fmt.Errorf("line %d: fee: %w", line, err)
+		}
+		if netCol >= 0 {
+			if row.Net, err = parseAmount(cell(netCol)); err != nil {
+				return nil, 0, fmt.Errorf("line %d: net amount: %w", line, err)
+			}
+		} else {
+			row.Net = row.Amount - row.Fee
+		}
+
+		if value := cell(dateCol); value != "" {
+			for _, layout := range mapping.DateLayouts {
+				if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+					row.Date = &t
+					break
+				}
+			}
+		}
+
+		rows = append(rows, row)
+	}
+	return rows, ignored, nil
+}
+
+// newCSVReader drops a UTF-8 byte order mark and picks ; as the delimiter when
+// the header uses it instead of commas, as spreadsheet exports often do.
+func newCSVReader(r io.Reader) *csv.Reader {
+	buffered := bufio.NewReader(r)
+	if bom, _ := buffered.Peek(3); bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+		buffered.Discard(3)
+	}
+
+	reader := csv.NewReader(buffered)
+	start, _ := buffered.Peek(buffered.Size())
+	header, _, _ := bytes.Cut(start, []byte("\n"))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	return reader
+}
+
+// parseAmount accepts plain numbers with an optional thousands separator
+// comma, e.g. "10000", "10000.00" or "10,000.00". Empty is zero.
+func parseAmount(value string) (float64, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), ",", "")
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+func containsFold(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package settlement
+
+import (
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		vendor      string
+		file        string
+		wantRows    []Row
+		wantIgnored int
+	}{
+		{
+			name:   "comma separated",
+			vendor: global_var.PGVendor.Midtrans,
+			file: "Order ID,Gross Amount,Fee,Net Amount,Transaction Status,Payment Type,Transaction Time\n" +
+				"A-1,\"10,000.00\",500,9500,settlement,bank_transfer,2026-03-01 10:00:00\n" +
+				"A-2,20000,0,20000,expire,gopay,2026-03-01 11:00:00\n",
+			wantRows:    []Row{{Line: 2, OrderID: "A-1", Amount: 10000, Fee: 500, Net: 9500, Status: "settlement", PaymentType: "bank_transfer"}},
+			wantIgnored: 1,
+		},
+		{
+			name:   "semicolon separated",
+			vendor: global_var.PGVendor.Midtrans,
+			file: "Order ID;Amount;Fee;Status\n" +
+				"A-1;10000;500;settlement\n" +
+				"A-2;25000;0;capture\n",
+			wantRows: []Row{
+				{Line: 2, OrderID: "A-1", Amount: 10000, Fee: 500, Net: 9500, Status: "settlement"},
+				{Line: 3, OrderID: "A-2", Amount: 25000, Net: 25000, Status: "capture"},
+			},
+		},
+		{
+			name:     "byte order mark",
+			vendor:   global_var.PGVendor.Xendit,
+			file:     "\xEF\xBB\xBFReference,Amount,Status\nX-1,15000,PAID\n",
+			wantRows: []Row{{Line: 2, OrderID: "X-1", Amount: 15000, Net: 15000, Status: "PAID"}},
+		},
+		{
+			name:     "header matched case insensitively and blank order ids skipped",
+			vendor:   global_var.PGVendor.Xendit,
+			file:     " external id ,AMOUNT\n,100\nX-2,200\n",
+			wantRows: []Row{{Line: 3, OrderID: "X-2", Amount: 200, Net: 200}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, ignored, err := Parse(tt.vendor, strings.NewReader(tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ignored != tt.wantIgnored {
+				t.Errorf("ignored = %d, want %d", ignored, tt.wantIgnored)
+			}
+			if len(rows) != len(tt.wantRows) {
+				t.Fatalf("got %d rows, want %d: %+v", len(rows), len(tt.wantRows), rows)
+			}
+			for i, want := range tt.wantRows {
+				got := rows[i]
+				got.Date = nil
+				if got != want {
+					t.Errorf("row %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	file := "Order ID,Amount,Status,Settlement Date\nA-1,100,settlement,14/03/2026\n"
+	rows, _, err := Parse(global_var.PGVendor.Midtrans, strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Date == nil {
+		t.Fatalf("rows = %+v, want one row with a date", rows)
+	}
+	if got := rows[0].Date.Format("2006-01-02"); got != "2026-03-14" {
+		t.Errorf("date = %s, want 2026-03-14", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		vendor  string
+		file    string
+		wantErr string
+	}{
+		{"unsupported vendor", global_var.PGVendor.Doku, "Order ID,Amount\n", "not supported"},
+		{"empty file", global_var.PGVendor.Midtrans, "", "empty"},
+		{"missing amount column", global_var.PGVendor.Midtrans, "Order ID,Fee\nA-1,1\n", "amount column"},
+		{"bad amount", global_var.PGVendor.Midtrans, "Order ID,Amount\nA-1,ten\n", "line 2: amount"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Parse(tt.vendor, strings.NewReader(tt.file))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	rows := []Row{
+		{Line: 2, OrderID: "B-1", Amount: 10000, Fee: 500, Net: 9500},
+		{Line: 3, OrderID: "B-2", Amount: 20000, Fee: 1000, Net: 19000},
+		{Line: 4, OrderID: "B-9", Amount: 5000, Net: 5000},
+	}
+	known := []db_var.PaymentGatewayTransactionT{
+		{OrderID: "B-1", Amount: 10000, Status: global_var.TxStatusPaid},
+		{OrderID: "B-2", Amount: 25000, Status: global_var.TxStatusPaid},
+	}
+	expected := []db_var.PaymentGatewayTransactionT{
+		{OrderID: "B-1", Amount: 10000, Status: global_var.TxStatusPaid},
+		{OrderID: "B-2", Amount: 25000, Status: global_var.TxStatusPaid},
+		{OrderID: "B-3", Amount: 7000, Status: global_var.TxStatusPartiallyRefunded},
+	}
+
+	report := Build(rows, known, expected)
+
+	if report.Matched != 1 || report.AmountMismatched != 1 || report.MissingInBridge != 1 || report.MissingInSettlement != 1 {
+		t.Errorf("counts = matched %d, mismatched %d, missing in bridge %d, missing in settlement %d, want 1 each",
+			report.Matched, report.AmountMismatched, report.MissingInBridge, report.MissingInSettlement)
+	}
+	if report.TotalGross != 35000 || report.TotalFee != 1500 || report.TotalNet != 33500 {
+		t.Errorf("totals = %v/%v/%v, want 35000/1500/33500", report.TotalGross, report.TotalFee, report.TotalNet)
+	}
+
+	want := []struct {
+		orderID, result string
+		bridgeAmount    int
+	}{
+		{"B-2", ResultAmountMismatch, 25000},
+		{"B-3", ResultMissingInSettlement, 7000},
+		{"B-9", ResultMissingInBridge, 0},
+		{"B-1", ResultMatched, 10000},
+	}
+	if len(report.Items) != len(want) {
+		t.Fatalf("got %d items, want %d: %+v", len(report.Items), len(want), report.Items)
+	}
+	for i, w := range want {
+		item := report.Items[i]
+		if item.OrderID != w.orderID || item.Result != w.result || item.BridgeAmount != w.bridgeAmount {
+			t.Errorf("item %d = %s %s %d, want %s %s %d", i, item.OrderID, item.Result, item.BridgeAmount, w.orderID, w.result, w.bridgeAmount)
+		}
+	}
+}
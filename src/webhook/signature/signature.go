@@ -0,0 +1,110 @@
+// Package signature signs and verifies the webhooks the bridge posts to
+// merchant callback URLs. It only depends on the standard library so merchant
+// services can import it directly:
+//
+//	err := signature.Verify(r.Header, body, []string{secret}, signature.DefaultTolerance)
+//
+// Every webhook carries two headers:
+//
+//	X-PGB-Webhook-Timestamp: unix seconds when the attempt was sent
+//	X-PGB-Webhook-Signature: v1=<hex>[,v1=<hex>]
+//
+// where each v1 value is hex(HMAC-SHA256(secret, timestamp + "." + body)).
+// While a secret is being rotated the bridge signs with both the new and the
+// previous secret, so a receiver holding either one accepts the webhook.
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderTimestamp = "X-PGB-Webhook-Timestamp"
+	HeaderSignature = "X-PGB-Webhook-Signature"
+
+	// Version is the scheme prefix of each signature in HeaderSignature.
+	Version = "v1"
+)
+
+// DefaultTolerance is how far the timestamp may be from the receiver clock.
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	ErrMissingHeaders    = errors.New("webhook signature headers missing")
+	ErrInvalidTimestamp  = errors.New("webhook timestamp invalid")
+	ErrTimestampTooOld   = errors.New("webhook timestamp outside tolerance")
+	ErrNoValidSignature  = errors.New("no valid webhook signature")
+	ErrNoSecretsProvided = errors.New("no webhook secrets provided")
+)
+
+// Compute returns hex(HMAC-SHA256(secret, timestamp + "." + body)).
+func Compute(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Header builds the HeaderSignature value, one v1 entry per secret.
+func Header(timestamp int64, body []byte, secrets ...string) string {
+	parts := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		parts = append(parts, Version+"="+Compute(secret, timestamp, body))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Verify checks the signature headers of a received webhook against any of
+// the given secrets and rejects timestamps further than tolerance from now.
+func Verify(header http.Header, body []byte, secrets []string, tolerance time.Duration) error {
+	return VerifyAt(header.Get(HeaderTimestamp), header.Get(HeaderSignature), body, secrets, tolerance, time.Now())
+}
+
+// VerifyAt is Verify with explicit header values and clock.
+func VerifyAt(timestampHeader, signatureHeader string, body []byte, secrets []string, tolerance time.Duration, now time.Time) error {
+	if len(secrets) == 0 {
+		return ErrNoSecretsProvided
+	}
+	if timestampHeader == "" || signatureHeader == "" {
+		return ErrMissingHeaders
+	}
+
+	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	if tolerance > 0 {
+		age := now.Sub(time.Unix(timestamp, 0))
+		if age > tolerance || age < -tolerance {
+			return ErrTimestampTooOld
+		}
+	}
+
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		expected := []byte(Compute(secret, timestamp, body))
+		for _, part := range strings.Split(signatureHeader, ",") {
+			version, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+			if !ok || version != Version {
+				continue
+			}
+			if hmac.Equal(expected, []byte(value)) {
+				return nil
+			}
+		}
+	}
+	return ErrNoValidSignature
+}
@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"encoding/json"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/provider"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const EventStatusChanged = "transaction.status_changed"
+
+// StatusChangeEvent is the normalized payload posted to the merchant CallbackURL.
+type StatusChangeEvent struct {
+	Event          string     `json:"event"`
+	OrderID        string     `json:"order_id"`
+	Status         string     `json:"status"`
+	PreviousStatus string     `json:"previous_status"`
+	VendorStatus   string     `json:"vendor_status"`
+	Amount         int        `json:"amount"`
+	PaymentMethod  string     `json:"payment_method"`
+	VendorCode     string     `json:"vendor_code"`
+	CreatedAt      time.Time  `json:"created_at"`
+	PaidAt         *time.Time `json:"paid_at,omitempty"`
+	OccurredAt     time.Time  `json:"occurred_at"`
+}
+
+// NewStatusChangeEvent builds the event for a transaction that moved from its
+// stored status to the one reported by the vendor.
+func NewStatusChangeEvent(transaction db_var.PaymentGatewayTransactionT, status *provider.TransactionStatus, occurredAt time.Time) StatusChangeEvent {
+	event := StatusChangeEvent{
+		Event:          EventStatusChanged,
+		OrderID:        transaction.OrderID,
+		Status:         status.Status,
+		PreviousStatus: transaction.Status,
+		VendorStatus:   status.VendorStatus,
+		Amount:         transaction.Amount,
+		PaymentMethod:  status.PaymentType,
+		VendorCode:     transaction.Vendor,
+		CreatedAt:      transaction.CreatedAt,
+		OccurredAt:     occurredAt,
+	}
+	if status.Status == global_var.TxStatusPaid {
+		event.PaidAt = &occurredAt
+	}
+	return event
+}
+
+// Enqueue stores the event for delivery to the credential CallbackURL. Pass the
+// transaction that changes the status so the event is only queued on commit.
+// Credentials without a CallbackURL are skipped.
+func Enqueue(credential db_var.PaymentGatewayCredentialT, event StatusChangeEvent, tx *gorm.DB) error {
+	if credential.CallbackURL == "" {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	delivery := db_var.WebhookDeliveryT{
+		OrganizationCode: credential.OrganizationCode,
+		VendorCode:       credential.Code,
+		OrderID:          event.OrderID,
+		EventType:        event.Event,
+		CallbackURL:      credential.CallbackURL,
+		Payload:          datatypes.JSON(payload),
+		Status:           global_var.WebhookStatusPending,
+		MaxAttempts:      MaxAttempts,
+		NextAttemptAt:    time.Now(),
+	}
+	return tx.Create(&delivery).Error
+}
@@ -0,0 +1,228 @@
+package webhook
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"pg_bridge_go/config"
+	"pg_bridge_go/db_var"
+	"pg_bridge_go/global_var"
+	"pg_bridge_go/helper"
+	"pg_bridge_go/logger"
+	"pg_bridge_go/webhook/signature"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+var (
+	// MaxAttempts is how many times a delivery is tried before it is dead-lettered.
+	MaxAttempts = 10
+	// DeliveryTimeout bounds how long a merchant endpoint may take to answer.
+	DeliveryTimeout = 10 * time.Second
+	// ClaimLease is how long a claimed delivery stays hidden from other
+	// workers. A worker that dies mid delivery releases it when this runs out.
+	ClaimLease = 2 * time.Minute
+	// PollInterval is how long an idle worker waits before looking again.
+	PollInterval = 2 * time.Second
+
+	BackoffBase = 30 * time.Second
+	BackoffMax  = 6 * time.Hour
+)
+
+const responseSnippetLimit = 1024
+
+var httpClient = &http.Client{}
+
+// StartWorkers starts n delivery workers in the background.
+func StartWorkers(n int) {
+	for i := 0; i < n; i++ {
+		go worker(i)
+	}
+	logger.Info("Webhook delivery workers started", zap.Int("workers", n))
+}
+
+func worker(id int) {
+	for {
+		delivery, err := claimNext()
+		if err != nil {
+			logger.Error("Failed to claim webhook delivery", zap.Int("worker", id), zap.Error(err))
+			time.Sleep(PollInterval)
+			continue
+		}
+		if delivery == nil {
+			time.Sleep(PollInterval)
+			continue
+		}
+		process(*delivery)
+	}
+}
+
+// claimNext locks the oldest due delivery, skipping rows other workers hold,
+// and leases it by pushing next_attempt_at forward.
+func claimNext() (*db_var.WebhookDeliveryT, error) {
+	// Polling would otherwise flood the SQL debug log
+	db := global_var.DB.Session(&gorm.Session{Logger: global_var.DB.Logger.LogMode(gormlogger.Warn)})
+
+	var delivery *db_var.WebhookDeliveryT
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var due []db_var.WebhookDeliveryT
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", global_var.WebhookStatusPending, time.Now()).
+			Order("next_attempt_at").
+			Limit(1).
+			Find(&due).Error
+		if err != nil || len(due) == 0 {
+			return err
+		}
+
+		delivery = &due[0]
+		return tx.Model(&db_var.WebhookDeliveryT{}).
+			Where("id = ?", delivery.ID).
+			Update("next_attempt_at", time.Now().Add(ClaimLease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func process(delivery db_var.WebhookDeliveryT) {
+	attemptNo := delivery.Attempts + 1
+	start := time.Now()
+	statusCode, snippet, err := send(delivery)
+	latency := time.Since(start)
+
+	attempt := db_var.WebhookAttemptT{
+		DeliveryID:      delivery.ID,
+		AttemptNo:       attemptNo,
+		StatusCode:      statusCode,
+		LatencyMs:       latency.Milliseconds(),
+		ResponseSnippet: snippet,
+	}
+
+	updates := map[string]interface{}{
+		"attempts":         attemptNo,
+		"last_status_code": statusCode,
+		"last_error":       "",
+	}
+
+	switch {
+	case err == nil:
+		now := time.Now()
+		updates["status"] = global_var.WebhookStatusDelivered
+		updates["delivered_at"] = &now
+	case attemptNo >= delivery.MaxAttempts:
+		attempt.Error = err.Error()
+		updates["last_error"] = err.Error()
+		updates["status"] = global_var.WebhookStatusDead
+		logger.Error("Webhook delivery dead-lettered",
+			zap.Uint64("delivery_id", delivery.ID),
+			zap.String("vendor_code", delivery.VendorCode),
+			zap.String("order_id", delivery.OrderID),
+			zap.Int("attempts", attemptNo),
+			zap.Error(err),
+		)
+	default:
+		attempt.Error = err.Error()
+		updates["last_error"] = err.Error()
+		updates["next_attempt_at"] = time.Now().Add(Backoff(attemptNo))
+	}
+
+	err = global_var.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&attempt).Error; err != nil {
+			return err
+		}
+		return tx.Model(&db_var.WebhookDeliveryT{}).Where("id = ?", delivery.ID).Updates(updates).Error
+	})
+	if err != nil {
+		// The lease runs out and the delivery is retried
+		logger.Error("Failed to record webhook attempt", zap.Uint64("delivery_id", delivery.ID), zap.Error(err))
+	}
+}
+
+// send signs and posts the stored payload and returns the HTTP status and the
+// start of the response body. Any non 2xx answer is an error.
+func send(delivery db_var.WebhookDeliveryT) (int, string, error) {
+	secrets, err := signingSecrets(delivery.VendorCode)
+	if err != nil {
+		return 0, "", err
+	}
+
+	req, err := http.NewRequest("POST", delivery.CallbackURL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-PGB-Event", delivery.EventType)
+	req.Header.Set("X-PGB-Delivery", strconv.FormatUint(delivery.ID, 10))
+
+	// Each attempt is signed with a fresh timestamp
+	if len(secrets) > 0 {
+		timestamp := time.Now().Unix()
+		req.Header.Set(signature.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(signature.HeaderSignature, signature.Header(timestamp, delivery.Payload, secrets...))
+	}
+
+	client := *httpClient
+	client.Timeout = DeliveryTimeout
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, responseSnippetLimit))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(body), fmt.Errorf("merchant endpoint returned HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(body), nil
+}
+
+// signingSecrets returns the decrypted webhook secrets of a credential: the
+// current one, plus the previous one while its rotation grace period lasts.
+func signingSecrets(vendorCode string) ([]string, error) {
+	var credential db_var.PaymentGatewayCredentialT
+	if err := global_var.DB.Where("code = ?", vendorCode).First(&credential).Error; err != nil {
+		return nil, fmt.Errorf("load credential %s: %w", vendorCode, err)
+	}
+
+	var secrets []string
+	if credential.WebhookSecret != "" {
+		secret, err := helper.Decrypt(credential.WebhookSecret, config.MasterKey)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secret)
+	}
+	if credential.WebhookSecretPrevious != "" && credential.WebhookSecretPreviousExpiresAt != nil && time.Now().Before(*credential.WebhookSecretPreviousExpiresAt) {
+		secret, err := helper.Decrypt(credential.WebhookSecretPrevious, config.MasterKey)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, nil
+}
+
+// Backoff returns the wait before the next try: exponential in the attempt
+// number, capped at BackoffMax, with the upper half randomized so retries from
+// one outage do not arrive together.
+func Backoff(attempt int) time.Duration {
+	delay := BackoffBase
+	for i := 1; i < attempt && delay < BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > BackoffMax {
+		delay = BackoffMax
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}